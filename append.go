@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func validateAppend(config *Config) error {
+	if !config.Append {
+		return nil
+	}
+	if config.Format != "json" && config.Format != "csv" {
+		return fmt.Errorf("--append supports json and csv output")
+	}
+	if config.Columns != "" {
+		return fmt.Errorf("--append can't be combined with --columns, the file's columns must be field names")
+	}
+	if config.Encoding != "utf-8" {
+		return fmt.Errorf("--append needs utf-8 output")
+	}
+	return nil
+}
+
+// Reads the repeaters already saved in a JSON or CSV output, or none if the file doesn't exist yet
+func loadExistingRecords(path string, config *Config) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s to append to: %w", path, err)
+	}
+	if config.Format == "json" {
+		var saved struct {
+			Results []map[string]interface{} `json:"results"`
+		}
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("%s isn't an rbdl JSON file to append to: %w", path, err)
+		}
+		return saved.Results, nil
+	}
+	records, err := parseCSVRecords(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s to append to: %w", path, err)
+	}
+	return records, nil
+}
+
+// Reads CSV output written with field names as headers back into records, skipping # comment lines
+func parseCSVRecords(data []byte, config *Config) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\uFEFF"))))
+	reader.Comma, _ = csvDelimiter(config.CSVDelimiter)
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var records []map[string]interface{}
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		record := make(map[string]interface{}, len(row))
+		for j, value := range row {
+			if value != "" {
+				record[rows[0][j]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Adds new results to the saved ones. A repeater already saved, going by recordKeys, is replaced by
+// its new listing in place, others go at the end; with --sort the merged list is sorted again.
+func appendRecords(existing, records []map[string]interface{}, config *Config) ([]map[string]interface{}, error) {
+	index := make(map[string]int, len(existing))
+	merged := append([]map[string]interface{}(nil), existing...)
+	for i, record := range merged {
+		for _, key := range recordKeys(record) {
+			index[key] = i
+		}
+	}
+	for _, record := range records {
+		keys := recordKeys(record)
+		position := len(merged)
+		for _, key := range keys {
+			if i, ok := index[key]; ok {
+				position = i
+				break
+			}
+		}
+		if position == len(merged) {
+			merged = append(merged, record)
+		} else {
+			merged[position] = record
+		}
+		for _, key := range keys {
+			index[key] = position
+		}
+	}
+	if config.Sort != "" {
+		keys, err := parseSortKeys(config.Sort)
+		if err != nil {
+			return nil, err
+		}
+		if err := sortRecords(merged, keys); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
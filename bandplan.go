@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// The amateur allocations of one region, loose enough to cover the variations between its countries
+type bandPlan struct {
+	name     string
+	segments []Band
+}
+
+var (
+	regionOnePlan = bandPlan{"IARU Region 1", []Band{
+		{Name: "10m", Min: 28.0, Max: 29.7},
+		{Name: "6m", Min: 50.0, Max: 54.0},
+		{Name: "4m", Min: 70.0, Max: 70.5},
+		{Name: "2m", Min: 144.0, Max: 146.0},
+		{Name: "70cm", Min: 430.0, Max: 440.0},
+		{Name: "23cm", Min: 1240.0, Max: 1300.0},
+	}}
+	regionTwoPlan = bandPlan{"IARU Region 2", []Band{
+		{Name: "10m", Min: 28.0, Max: 29.7},
+		{Name: "6m", Min: 50.0, Max: 54.0},
+		{Name: "2m", Min: 144.0, Max: 148.0},
+		{Name: "1.25m", Min: 219.0, Max: 225.0},
+		{Name: "70cm", Min: 420.0, Max: 450.0},
+		{Name: "33cm", Min: 902.0, Max: 928.0},
+		{Name: "23cm", Min: 1240.0, Max: 1300.0},
+	}}
+	regionThreePlan = bandPlan{"IARU Region 3", []Band{
+		{Name: "10m", Min: 28.0, Max: 29.7},
+		{Name: "6m", Min: 50.0, Max: 54.0},
+		{Name: "2m", Min: 144.0, Max: 148.0},
+		{Name: "70cm", Min: 420.0, Max: 450.0},
+		{Name: "23cm", Min: 1240.0, Max: 1300.0},
+	}}
+	// GMRS repeaters are licensed separately from amateur ones but listed alongside them
+	unitedStatesPlan = bandPlan{"US", append([]Band{{Name: "GMRS", Min: 462.55, Max: 467.725}}, regionTwoPlan.segments...)}
+)
+
+// Countries outside Region 2 that RepeaterBook lists, by the spelling in countryNames
+var planRegions = map[string]int{
+	"austria": 1, "belgium": 1, "bosnia and herzegovina": 1, "bulgaria": 1, "croatia": 1, "cyprus": 1,
+	"czech republic": 1, "denmark": 1, "estonia": 1, "finland": 1, "france": 1, "germany": 1, "greece": 1,
+	"hungary": 1, "iceland": 1, "ireland": 1, "israel": 1, "italy": 1, "latvia": 1, "lithuania": 1,
+	"luxembourg": 1, "malta": 1, "netherlands": 1, "norway": 1, "poland": 1, "portugal": 1, "romania": 1,
+	"serbia": 1, "slovakia": 1, "slovenia": 1, "south africa": 1, "spain": 1, "sweden": 1,
+	"switzerland": 1, "turkey": 1, "ukraine": 1, "united kingdom": 1,
+	"australia": 3, "china": 3, "india": 3, "indonesia": 3, "japan": 3, "malaysia": 3, "new zealand": 3,
+	"philippines": 3, "singapore": 3, "south korea": 3, "taiwan": 3, "thailand": 3,
+}
+
+// Listings without a country come from the North American endpoint and carry a state or province
+func recordBandPlan(record map[string]interface{}) bandPlan {
+	country := strings.ToLower(recordString(record, "Country"))
+	if country == "" || country == "united states" {
+		return unitedStatesPlan
+	}
+	switch planRegions[country] {
+	case 1:
+		return regionOnePlan
+	case 3:
+		return regionThreePlan
+	}
+	return regionTwoPlan
+}
+
+func (plan bandPlan) contains(freq float64) bool {
+	for _, segment := range plan.segments {
+		if freq >= segment.Min-0.0005 && freq <= segment.Max+0.0005 {
+			return true
+		}
+	}
+	return false
+}
+
+// Warns about repeaters whose output or input falls outside their region's band plan, and about
+// offsets the US and Canadian plan doesn't expect, on stderr and in the --band-plan-report file
+func checkBandPlan(records []map[string]interface{}, config *Config) error {
+	var warnings []string
+	for _, record := range records {
+		output, ok := recordFloat(record, "Frequency")
+		if !ok || output <= 0 {
+			continue
+		}
+		call := recordString(record, "Callsign")
+		plan := recordBandPlan(record)
+		if !plan.contains(output) {
+			warnings = append(warnings, fmt.Sprintf("%s lists an output of %.4f MHz, outside the %s band plan", call, output, plan.name))
+			continue
+		}
+		input, ok := recordFloat(record, "Input Freq")
+		if ok && input > 0 && math.Abs(input-output) > 0.0005 && !plan.contains(input) {
+			warnings = append(warnings, fmt.Sprintf("%s on %.4f lists an input of %.4f MHz, outside the %s band plan", call, output, input, plan.name))
+		}
+	}
+	warnings = append(warnings, checkOffsets(records, config)...)
+	return reportBandPlan(warnings, config)
+}
+
+const maxBandPlanWarnings = 5
+
+// Records are parsed again for each output of a run, and once per file with --split-by, so every
+// warning is printed only the first time it comes up
+var (
+	bandPlanWarnings []string
+	bandPlanSeen     = make(map[string]bool)
+)
+
+func reportBandPlan(warnings []string, config *Config) error {
+	for _, warning := range warnings {
+		if bandPlanSeen[warning] {
+			continue
+		}
+		bandPlanSeen[warning] = true
+		bandPlanWarnings = append(bandPlanWarnings, warning)
+		switch n := len(bandPlanWarnings); {
+		case n <= maxBandPlanWarnings:
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		case n == maxBandPlanWarnings+1 && config.BandPlanReport == "":
+			fmt.Fprintf(os.Stderr, "Warning: more repeaters break the band plan, use --band-plan-report to list them all\n")
+		case n == maxBandPlanWarnings+1:
+			fmt.Fprintf(os.Stderr, "Warning: more repeaters break the band plan, see %s\n", config.BandPlanReport)
+		}
+	}
+	if config.BandPlanReport == "" {
+		return nil
+	}
+	var report strings.Builder
+	for _, warning := range bandPlanWarnings {
+		report.WriteString(warning + "\n")
+	}
+	if err := os.WriteFile(config.BandPlanReport, []byte(report.String()), 0644); err != nil {
+		return fmt.Errorf("writing --band-plan-report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Band struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// Ordered by frequency so grouped output reads from low to high
+var bands = []Band{
+	{Name: "10m", Min: 28.0, Max: 29.7},
+	{Name: "6m", Min: 50.0, Max: 54.0},
+	{Name: "2m", Min: 144.0, Max: 148.0},
+	{Name: "1.25m", Min: 219.0, Max: 225.0},
+	{Name: "70cm", Min: 420.0, Max: 450.0},
+	{Name: "GMRS", Min: 462.0, Max: 468.0},
+	{Name: "33cm", Min: 902.0, Max: 928.0},
+	{Name: "23cm", Min: 1240.0, Max: 1300.0},
+}
+
+const otherBand = "Other"
+
+func bandForFrequency(freq float64) string {
+	for _, band := range bands {
+		if freq >= band.Min && freq <= band.Max {
+			return band.Name
+		}
+	}
+	return otherBand
+}
+
+// Returns the position of a band name in the bands table, with unknown bands sorted last
+func bandIndex(name string) int {
+	for i, band := range bands {
+		if band.Name == name {
+			return i
+		}
+	}
+	return len(bands)
+}
+
+func findBand(name string) (Band, bool) {
+	for _, band := range bands {
+		if strings.EqualFold(band.Name, strings.TrimSpace(name)) {
+			return band, true
+		}
+	}
+	return Band{}, false
+}
+
+// Looks up a comma-separated list of band names such as "2m,70cm"
+func parseBands(list string) ([]Band, error) {
+	var selected []Band
+	for _, name := range splitList(list) {
+		band, ok := findBand(name)
+		if !ok {
+			names := make([]string, len(bands))
+			for i, b := range bands {
+				names[i] = b.Name
+			}
+			return nil, fmt.Errorf("unknown band %q, expected one of: %s", name, strings.Join(names, ", "))
+		}
+		selected = append(selected, band)
+	}
+	return selected, nil
+}
+
+func inBands(freq float64, selected []Band) bool {
+	for _, band := range selected {
+		if freq >= band.Min && freq <= band.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses --freq-min and --freq-max in MHz, either bound left open when not given
+func frequencyRange(config *Config) (float64, float64, error) {
+	low, high := 0.0, math.Inf(1)
+	if config.FreqMin != "" {
+		f, err := strconv.ParseFloat(config.FreqMin, 64)
+		if err != nil || f < 0 {
+			return 0, 0, fmt.Errorf("--freq-min must be a frequency in MHz, e.g. 440")
+		}
+		low = f
+	}
+	if config.FreqMax != "" {
+		f, err := strconv.ParseFloat(config.FreqMax, 64)
+		if err != nil || f < 0 {
+			return 0, 0, fmt.Errorf("--freq-max must be a frequency in MHz, e.g. 450")
+		}
+		high = f
+	}
+	if low > high {
+		return 0, 0, fmt.Errorf("--freq-min can't be above --freq-max")
+	}
+	return low, high, nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const browsePageSize = 20
+
+// Key columns shown in terminal tables, with the short names browse sort and filter also accept
+var tableColumns = []struct {
+	name  string
+	field string
+}{
+	{"call", "Callsign"},
+	{"freq", "Frequency"},
+	{"input", "Input Freq"},
+	{"pl", "PL"},
+	{"city", "Nearest City"},
+	{"state", "State"},
+	{"use", "Use"},
+	{"status", "Operational Status"},
+}
+
+const browseHelp = `Commands:
+  list [page]        show the current view, 20 repeaters a page
+  sort COLUMN [desc] sort by call, freq, input, pl, city, state, use, status or any field name
+  filter TEXT        keep repeaters with TEXT in any column, or COLUMN=TEXT in one
+  reset              undo sorting and filters
+  show N             print every field of repeater N
+  fav N...           mark or unmark repeaters as favorites
+  favs               show only favorites
+  export FILE [FMT]  save the favorites, or the current view if there are none
+  quit               leave
+`
+
+// Interactive session over fetched repeaters, numbered by their position in records
+type browser struct {
+	config    *Config
+	records   []map[string]interface{}
+	view      []int
+	favorites map[int]bool
+	out       io.Writer
+}
+
+// Fetches a search like rbdl fetch, then reads commands for sorting, filtering, marking favorites
+// and exporting the selection
+func runBrowseCommand(args []string) int {
+	fs := flag.NewFlagSet("rbdl browse", flag.ExitOnError)
+	config := parseFlags(fs, args)
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	config.StateID, config.Country, _ = resolveStateIDs(config.StateID, config.Country)
+	if err := resolveLocation(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving location: %v\n", err)
+		return 1
+	}
+	responses, err := fetchAll(expandQueries(config), config.Throttle, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching data: %v\n", err)
+		return exitCode(err)
+	}
+	data, err := mergeResponses(responses)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging results: %v\n", err)
+		return 1
+	}
+	b, err := newBrowser(data, config, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	b.run(os.Stdin)
+	return 0
+}
+
+func newBrowser(data []byte, config *Config, out io.Writer) (*browser, error) {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return nil, err
+	}
+	b := &browser{config: config, records: records, favorites: make(map[int]bool), out: out}
+	b.reset()
+	return b, nil
+}
+
+func (b *browser) reset() {
+	b.view = make([]int, len(b.records))
+	for i := range b.view {
+		b.view[i] = i
+	}
+}
+
+func (b *browser) run(in io.Reader) {
+	fmt.Fprintf(b.out, "%d repeaters, type help for commands\n", len(b.records))
+	b.list(1)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(b.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(b.out)
+			return
+		}
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] == "quit" || words[0] == "exit" || words[0] == "q" {
+			return
+		}
+		if err := b.command(words[0], words[1:]); err != nil {
+			fmt.Fprintf(b.out, "Error: %v\n", err)
+		}
+	}
+}
+
+func (b *browser) command(name string, args []string) error {
+	switch name {
+	case "help", "?":
+		fmt.Fprint(b.out, browseHelp)
+	case "list", "ls":
+		page := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("page must be a positive number")
+			}
+			page = n
+		}
+		b.list(page)
+	case "sort":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: sort COLUMN [desc]")
+		}
+		b.sort(browseField(args[0]), len(args) > 1 && args[1] == "desc")
+		b.list(1)
+	case "filter":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: filter TEXT or filter COLUMN=TEXT")
+		}
+		b.filter(strings.Join(args, " "))
+		b.list(1)
+	case "reset":
+		b.reset()
+		b.list(1)
+	case "show":
+		indexes, err := b.parseIndexes(args)
+		if err != nil {
+			return err
+		}
+		for _, i := range indexes {
+			printRecord(b.out, b.records[i])
+			fmt.Fprintln(b.out)
+		}
+	case "fav":
+		indexes, err := b.parseIndexes(args)
+		if err != nil {
+			return err
+		}
+		for _, i := range indexes {
+			if b.favorites[i] {
+				delete(b.favorites, i)
+			} else {
+				b.favorites[i] = true
+			}
+		}
+		fmt.Fprintf(b.out, "%d favorites\n", len(b.favorites))
+	case "favs":
+		b.view = b.selection()
+		b.list(1)
+	case "export":
+		if len(args) == 0 || len(args) > 2 {
+			return fmt.Errorf("usage: export FILE [FORMAT]")
+		}
+		return b.export(args[0], args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, type help for commands", name)
+	}
+	return nil
+}
+
+// Maps a short column name to its field, anything else is taken as a field name
+func browseField(name string) string {
+	for _, column := range tableColumns {
+		if strings.EqualFold(column.name, name) || strings.EqualFold(column.field, name) {
+			return column.field
+		}
+	}
+	return name
+}
+
+func (b *browser) list(page int) {
+	if len(b.view) == 0 {
+		fmt.Fprintf(b.out, "No repeaters match, use reset to start over\n")
+		return
+	}
+	pages := (len(b.view) + browsePageSize - 1) / browsePageSize
+	page = min(page, pages)
+	w := tabwriter.NewWriter(b.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "#\t")
+	for _, column := range tableColumns {
+		fmt.Fprintf(w, "\t%s", column.field)
+	}
+	fmt.Fprintln(w)
+	start := (page - 1) * browsePageSize
+	for _, i := range b.view[start:min(start+browsePageSize, len(b.view))] {
+		mark := ""
+		if b.favorites[i] {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%d\t%s", i+1, mark)
+		for _, column := range tableColumns {
+			fmt.Fprintf(w, "\t%s", recordString(b.records[i], column.field))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	if pages > 1 {
+		fmt.Fprintf(b.out, "Page %d of %d, list N for another\n", page, pages)
+	}
+}
+
+func (b *browser) sort(field string, descending bool) {
+	if found, ok := findField(b.records, field); ok {
+		field = found
+	}
+	sort.SliceStable(b.view, func(i, j int) bool {
+		return compareField(b.records[b.view[i]], b.records[b.view[j]], field, descending) < 0
+	})
+}
+
+func (b *browser) filter(query string) {
+	fields := make([]string, 0, len(tableColumns))
+	for _, column := range tableColumns {
+		fields = append(fields, column.field)
+	}
+	if column, text, ok := strings.Cut(query, "="); ok {
+		fields = []string{browseField(strings.TrimSpace(column))}
+		query = text
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	var view []int
+	for _, i := range b.view {
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(recordString(b.records[i], field)), query) {
+				view = append(view, i)
+				break
+			}
+		}
+	}
+	b.view = view
+}
+
+// Repeater numbers as listed, which stay the same through sorting and filtering
+func (b *browser) parseIndexes(args []string) ([]int, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("give one or more repeater numbers")
+	}
+	var indexes []int
+	for _, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(b.records) {
+			return nil, fmt.Errorf("no repeater %s, numbers run from 1 to %d", arg, len(b.records))
+		}
+		indexes = append(indexes, n-1)
+	}
+	return indexes, nil
+}
+
+// The favorites in view order, or the whole view when none are marked
+func (b *browser) selection() []int {
+	if len(b.favorites) == 0 {
+		return b.view
+	}
+	var selected []int
+	for _, i := range b.view {
+		if b.favorites[i] {
+			selected = append(selected, i)
+		}
+	}
+	for i := range b.records {
+		if b.favorites[i] && !containsIndex(selected, i) {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+func containsIndex(indexes []int, n int) bool {
+	for _, i := range indexes {
+		if i == n {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *browser) export(path string, format []string) error {
+	// The format named, or else the one the file's extension suggests unless --format already fits it
+	config := *b.config
+	ext := strings.ToLower(filepath.Ext(path))
+	if len(format) > 0 {
+		config.Format = strings.ToLower(format[0])
+	} else if detected, ok := formatExtensions[ext]; ok && formatExtension(config.Format) != ext {
+		config.Format = detected
+	}
+	if !isOutputFormat(config.Format) {
+		return fmt.Errorf("format must be one of: %s", strings.Join(outputFormats, ", "))
+	}
+	selected := b.selection()
+	if len(selected) == 0 {
+		return fmt.Errorf("nothing to export")
+	}
+	// The records listed are exported as they are, without swapping, converting or filtering them again
+	results := make([]map[string]interface{}, 0, len(selected))
+	for _, i := range selected {
+		results = append(results, b.records[i])
+	}
+	config.processed = true
+	data, err := json.Marshal(map[string]interface{}{"count": len(results), "results": results})
+	if err != nil {
+		return fmt.Errorf("encoding selection: %w", err)
+	}
+	if err := saveToFile(path, data, &config); err != nil {
+		return fmt.Errorf("saving file: %w", err)
+	}
+	fmt.Fprintf(b.out, "Saved %d repeaters to: %s\n", len(results), path)
+	return nil
+}
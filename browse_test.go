@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Listings without a State ID or Rptr ID have no record key, and static channels aren't in the
+// API's results at all, so exports must not look repeaters up by key
+func TestBrowseExportRecordsWithoutIDs(t *testing.T) {
+	dir := t.TempDir()
+	static := filepath.Join(dir, "static.csv")
+	if err := os.WriteFile(static, []byte("Callsign,Frequency,Notes\nNET,146.550,Club net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"count": 2, "results": [
+		{"Callsign": "W7AAA", "Frequency": "146.88000", "Input Freq": "146.28000", "PL": "100.0", "Country": "United States", "State": "Montana"},
+		{"Callsign": "W7BBB", "Frequency": "147.00000", "Input Freq": "147.60000", "PL": "123.0", "Country": "United States", "State": "Montana"}
+	]}`)
+	config := &Config{
+		Format:         "json",
+		Force:          true,
+		Quiet:          true,
+		Distance:       "50mi",
+		CSVDelimiter:   "comma",
+		CSVQuote:       "minimal",
+		Encoding:       "utf-8",
+		StaticChannels: static,
+		Limit:          3,
+	}
+	var out bytes.Buffer
+	b, err := newBrowser(data, config, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.records) != 3 {
+		t.Fatalf("browsing %d records, want 2 repeaters and a static channel", len(b.records))
+	}
+	path := filepath.Join(dir, "export.json")
+	b.run(strings.NewReader("fav 2 3\nexport " + path + "\nquit\n"))
+	if strings.Contains(out.String(), "Error") {
+		t.Fatalf("export failed:\n%s", out.String())
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(saved, &response); err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	for _, record := range response.Results {
+		if record == nil {
+			t.Fatalf("export wrote a null record:\n%s", saved)
+		}
+		calls = append(calls, recordString(record, "Callsign"))
+	}
+	if got, want := strings.Join(calls, ","), "W7BBB,NET"; got != want {
+		t.Errorf("exported %s, want %s", got, want)
+	}
+}
+
+// Exports convert the records listed, so steps that already changed them must not run again
+func TestBrowseExportDoesNotReapply(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`{"count": 2, "results": [
+		{"Callsign": "W7AAA", "Frequency": "146.88000", "Input Freq": "146.28000", "Elevation": "1000", "Country": "United States", "State": "Montana"},
+		{"Callsign": "W7BBB", "Frequency": "147.00000", "Input Freq": "147.60000", "Elevation": "2000", "Country": "United States", "State": "Montana"}
+	]}`)
+	config := &Config{
+		Format:       "json",
+		Force:        true,
+		Quiet:        true,
+		Distance:     "50mi",
+		CSVDelimiter: "comma",
+		CSVQuote:     "minimal",
+		Encoding:     "utf-8",
+		SwapRxTx:     true,
+		Units:        unitsMetric,
+	}
+	var out bytes.Buffer
+	b, err := newBrowser(data, config, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "export.json")
+	b.run(strings.NewReader("export " + path + "\nquit\n"))
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	var response struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(saved, &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Results) != 2 {
+		t.Fatalf("exported %d repeaters, want 2", len(response.Results))
+	}
+	first := response.Results[0]
+	if got := recordString(first, "Frequency"); got != "146.28000" {
+		t.Errorf("Frequency = %s, want the input 146.28000 swapped once", got)
+	}
+	if got := recordString(first, "Elevation"); got != "305" {
+		t.Errorf("Elevation = %s, want 305 meters converted once", got)
+	}
+}
+
+// The CHIRP export still knows the channels are swapped, so they decode the uplink tone
+func TestBrowseExportKeepsSwapForCHIRP(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`{"count": 1, "results": [
+		{"Callsign": "W7AAA", "Frequency": "146.88000", "Input Freq": "146.28000", "PL": "100.0", "Country": "United States", "State": "Montana"}
+	]}`)
+	config := &Config{
+		Format:       "chirp",
+		Force:        true,
+		Quiet:        true,
+		Distance:     "50mi",
+		CSVDelimiter: "comma",
+		CSVQuote:     "minimal",
+		Encoding:     "utf-8",
+		SwapRxTx:     true,
+	}
+	var out bytes.Buffer
+	b, err := newBrowser(data, config, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "export.csv")
+	b.run(strings.NewReader("export " + path + "\nquit\n"))
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("exported %d rows, want a header and one channel", len(rows))
+	}
+	row := make(map[string]string)
+	for i, header := range rows[0] {
+		row[header] = rows[1][i]
+	}
+	if row["Frequency"] != "146.280000" || row["Tone"] != "TSQL" {
+		t.Errorf("Frequency %s, Tone %s, want the input 146.280000 decoding the uplink tone with TSQL", row["Frequency"], row["Tone"])
+	}
+}
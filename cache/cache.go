@@ -0,0 +1,179 @@
+// Package cache provides an http.RoundTripper that caches RepeaterBook API responses on disk.
+//
+// Wrapping a client's transport gives embedding applications the same caching the rbdl CLI uses:
+//
+//	client := &http.Client{Transport: cache.New(dir, time.Hour)}
+//
+// Responses younger than the TTL are served without touching the network. Stale responses that
+// carried an ETag or Last-Modified header are revalidated with a conditional request, and a 304
+// reply refreshes the stored copy rather than downloading the data again.
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Header added to stored responses recording when they were fetched
+const storedHeader = "X-Rbdl-Cached-At"
+
+// StatusHeader is set on every response passing through a Transport to "hit", "revalidated" or "miss"
+const StatusHeader = "X-Rbdl-Cache"
+
+// Transport caches successful GET responses in Dir for TTL.
+type Transport struct {
+	// Dir holds one file per cached URL and is created on first use.
+	Dir string
+	// TTL is how long a response is served without revalidation.
+	TTL time.Duration
+	// Base performs the actual requests; http.DefaultTransport is used when nil.
+	Base http.RoundTripper
+}
+
+// New returns a Transport caching responses in dir for ttl, on top of http.DefaultTransport.
+func New(dir string, ttl time.Duration) *Transport {
+	return &Transport{Dir: dir, TTL: ttl}
+}
+
+// RoundTrip serves req from the cache when possible, and stores successful responses otherwise.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet {
+		return base.RoundTrip(req)
+	}
+	path := t.path(req)
+	cached, storedAt := t.load(path, req)
+	if cached != nil && time.Since(storedAt) < t.TTL {
+		cached.Header.Set(StatusHeader, "hit")
+		return cached, nil
+	}
+	outgoing := req
+	if cached != nil {
+		// Only revalidate when the server gave us something to revalidate with
+		etag := cached.Header.Get("ETag")
+		modified := cached.Header.Get("Last-Modified")
+		if etag != "" || modified != "" {
+			outgoing = req.Clone(req.Context())
+			if etag != "" {
+				outgoing.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				outgoing.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+	resp, err := base.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		if err := t.store(path, cached); err != nil {
+			return nil, err
+		}
+		refreshed, _ := t.load(path, req)
+		if refreshed == nil {
+			return cached, nil
+		}
+		refreshed.Header.Set(StatusHeader, "revalidated")
+		return refreshed, nil
+	}
+	if cached != nil {
+		cached.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	if err := t.store(path, resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	stored, _ := t.load(path, req)
+	if stored == nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	stored.Header.Set(StatusHeader, "miss")
+	return stored, nil
+}
+
+// Bodies returns the body of every cached response, however old, for reading the cache offline.
+// Files that can't be read are skipped.
+func (t *Transport) Bodies() ([][]byte, error) {
+	paths, err := filepath.Glob(filepath.Join(t.Dir, "*.http"))
+	if err != nil {
+		return nil, err
+	}
+	var bodies [][]byte
+	for _, path := range paths {
+		resp, _ := t.load(path, nil)
+		if resp == nil {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil {
+			bodies = append(bodies, body)
+		}
+	}
+	return bodies, nil
+}
+
+// Clear removes every cached response.
+func (t *Transport) Clear() error {
+	return os.RemoveAll(t.Dir)
+}
+
+// Responses don't vary by User-Agent, so the URL alone identifies them
+func (t *Transport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return filepath.Join(t.Dir, hex.EncodeToString(sum[:])+".http")
+}
+
+func (t *Transport) load(path string, req *http.Request) (*http.Response, time.Time) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	storedAt, err := time.Parse(time.RFC3339Nano, resp.Header.Get(storedHeader))
+	if err != nil {
+		resp.Body.Close()
+		return nil, time.Time{}
+	}
+	resp.Header.Del(storedHeader)
+	return resp, storedAt
+}
+
+// Writes the full response to disk, stamped with the current time
+func (t *Transport) store(path string, resp *http.Response) error {
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	resp.Header.Set(storedHeader, time.Now().UTC().Format(time.RFC3339Nano))
+	resp.Header.Del(StatusHeader)
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return err
+	}
+	// Write then rename so a concurrent reader never sees a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, dump, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A server counting its requests, answering with an ETag and honoring If-None-Match
+func newServer(t *testing.T, status int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(status)
+		io.WriteString(w, `{"count":1}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func get(t *testing.T, client *http.Client, method, url string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func storedAt(t *testing.T, transport *Transport, url string) time.Time {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	resp, at := transport.load(transport.path(req), req)
+	if resp == nil {
+		t.Fatalf("nothing stored for %s", url)
+	}
+	resp.Body.Close()
+	return at
+}
+
+func TestMissStoresResponse(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	transport := New(t.TempDir(), time.Hour)
+	client := &http.Client{Transport: transport}
+	resp, body := get(t, client, http.MethodGet, server.URL+"/export")
+	if got := resp.Header.Get(StatusHeader); got != "miss" {
+		t.Errorf("%s = %q, want miss", StatusHeader, got)
+	}
+	if body != `{"count":1}` {
+		t.Errorf("body = %q", body)
+	}
+	if *requests != 1 {
+		t.Errorf("server saw %d requests, want 1", *requests)
+	}
+	storedAt(t, transport, server.URL+"/export")
+}
+
+func TestFreshHitServedFromDisk(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	client := &http.Client{Transport: New(t.TempDir(), time.Hour)}
+	get(t, client, http.MethodGet, server.URL+"/export")
+	resp, body := get(t, client, http.MethodGet, server.URL+"/export")
+	if got := resp.Header.Get(StatusHeader); got != "hit" {
+		t.Errorf("%s = %q, want hit", StatusHeader, got)
+	}
+	if body != `{"count":1}` {
+		t.Errorf("body = %q", body)
+	}
+	if *requests != 1 {
+		t.Errorf("server saw %d requests, want 1", *requests)
+	}
+	// Other URLs are cached separately
+	get(t, client, http.MethodGet, server.URL+"/export?state_id=30")
+	if *requests != 2 {
+		t.Errorf("server saw %d requests, want 2", *requests)
+	}
+}
+
+func TestNotModifiedRefreshesStoredTime(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	// A TTL of zero revalidates every request
+	transport := New(t.TempDir(), 0)
+	client := &http.Client{Transport: transport}
+	url := server.URL + "/export"
+	get(t, client, http.MethodGet, url)
+	first := storedAt(t, transport, url)
+	time.Sleep(10 * time.Millisecond)
+	resp, body := get(t, client, http.MethodGet, url)
+	if got := resp.Header.Get(StatusHeader); got != "revalidated" {
+		t.Errorf("%s = %q, want revalidated", StatusHeader, got)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want the stored 200", resp.StatusCode)
+	}
+	if body != `{"count":1}` {
+		t.Errorf("body = %q, want the stored body", body)
+	}
+	if *requests != 2 {
+		t.Errorf("server saw %d requests, want 2", *requests)
+	}
+	if refreshed := storedAt(t, transport, url); !refreshed.After(first) {
+		t.Errorf("stored time %v not refreshed from %v", refreshed, first)
+	}
+}
+
+func TestUncachedRequestsPassThrough(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+	}{
+		{"post", http.MethodPost, http.StatusOK},
+		{"server error", http.MethodGet, http.StatusInternalServerError},
+		{"rate limited", http.MethodGet, http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := newServer(t, test.status)
+			dir := t.TempDir()
+			client := &http.Client{Transport: New(dir, time.Hour)}
+			for i := 0; i < 2; i++ {
+				resp, _ := get(t, client, test.method, server.URL+"/export")
+				if resp.StatusCode != test.status {
+					t.Errorf("status = %d, want %d", resp.StatusCode, test.status)
+				}
+				if got := resp.Header.Get(StatusHeader); got != "" {
+					t.Errorf("%s = %q on an uncached response", StatusHeader, got)
+				}
+			}
+			if *requests != 2 {
+				t.Errorf("server saw %d requests, want both", *requests)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 0 {
+				t.Errorf("cache holds %d files, want none", len(entries))
+			}
+		})
+	}
+}
+
+func TestBodiesAndClear(t *testing.T) {
+	server, _ := newServer(t, http.StatusOK)
+	transport := New(t.TempDir(), time.Hour)
+	client := &http.Client{Transport: transport}
+	get(t, client, http.MethodGet, server.URL+"/a")
+	get(t, client, http.MethodGet, server.URL+"/b")
+	bodies, err := transport.Bodies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || !strings.Contains(string(bodies[0]), "count") {
+		t.Errorf("Bodies() = %q, want both responses", bodies)
+	}
+	if err := transport.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if bodies, _ := transport.Bodies(); len(bodies) != 0 {
+		t.Errorf("Bodies() after Clear = %d responses, want none", len(bodies))
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Longest channel names common radios store, for --name-length
+var radioNameLengths = map[string]int{
+	"uv5r":     7,
+	"uv82":     7,
+	"ft60":     6,
+	"ft65":     6,
+	"ft2900":   6,
+	"ic2730":   6,
+	"thd72":    8,
+	"anytone":  16,
+	"at878":    16,
+	"md380":    16,
+	"opengd77": 16,
+}
+
+// A name length given as a number, or as a radio model like uv5r or FT-60. Zero means no limit.
+func parseNameLength(spec string) (int, error) {
+	if spec == "" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("--name-length must be a positive number or a radio model")
+		}
+		return n, nil
+	}
+	model := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, spec)
+	if n, ok := radioNameLengths[model]; ok {
+		return n, nil
+	}
+	models := make([]string, 0, len(radioNameLengths))
+	for name := range radioNameLengths {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	return 0, fmt.Errorf("unknown radio %q for --name-length, give a number or one of: %s", spec, strings.Join(models, ", "))
+}
+
+// Channel names are rendered from the --channel-name template and cut to fit the radio
+type channelNamer struct {
+	tmpl  *template.Template
+	limit int
+}
+
+func newChannelNamer(config *Config) (*channelNamer, error) {
+	limit, err := parseNameLength(config.NameLength)
+	if err != nil {
+		return nil, err
+	}
+	namer := &channelNamer{limit: limit}
+	if config.ChannelName != "" {
+		tmpl, err := template.New("channel-name").Funcs(templateFuncs).Option("missingkey=zero").Parse(config.ChannelName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --channel-name: %w", err)
+		}
+		namer.tmpl = tmpl
+	}
+	return namer, nil
+}
+
+// Renders the template, or keeps the default name without one, before fitting it to the radio.
+// Short column names such as .City and .Call work alongside the full field names.
+func (n *channelNamer) name(record map[string]interface{}, fallback string) (string, error) {
+	name := fallback
+	if n.tmpl != nil {
+		fields := templateRecord(record)
+		for _, column := range tableColumns {
+			alias := strings.ToUpper(column.name[:1]) + column.name[1:]
+			if _, ok := fields[alias]; !ok {
+				fields[alias] = fields[column.field]
+			}
+		}
+		var buf bytes.Buffer
+		if err := n.tmpl.Execute(&buf, fields); err != nil {
+			return "", fmt.Errorf("rendering channel name for %s: %w", recordString(record, "Callsign"), err)
+		}
+		name = strings.Join(strings.Fields(buf.String()), " ")
+	}
+	return name, nil
+}
+
+// Cuts a name to the limit, keeping a suffix like " TA" whole so derived channels stay distinguishable
+func (n *channelNamer) fit(name, suffix string) string {
+	if n.limit > 0 && len([]rune(suffix)) < n.limit {
+		name = strings.TrimSpace(truncate(name, n.limit-len([]rune(suffix))))
+	}
+	name = strings.TrimSpace(name + suffix)
+	if n.limit > 0 {
+		name = truncate(name, n.limit)
+	}
+	return name
+}
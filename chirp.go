@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+var chirpHeaders = []string{
+	"Location", "Name", "Frequency", "Duplex", "Offset", "Tone", "rToneFreq", "cToneFreq",
+	"DtcsCode", "DtcsPolarity", "RxDtcsCode", "CrossMode", "Mode", "TStep", "Skip", "Power",
+	"Comment", "URCALL", "RPT1CALL", "RPT2CALL", "DVCODE",
+}
+
+// Repeaters with offsets larger than this are cross-band and programmed as split frequencies
+const chirpSplitThreshold = 50.0
+
+func saveToCHIRP(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w to write", errNoResults)
+	}
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer file.Close()
+	// CHIRP only imports its own comma-separated layout, so the CSV dialect flags don't apply
+	writer := csv.NewWriter(encodedWriter(file, config.Encoding))
+	defer writer.Flush()
+	if err := writer.Write(chirpHeaders); err != nil {
+		return fmt.Errorf("writing headers: %w", err)
+	}
+	namer, err := newChannelNamer(config)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]string
+	for _, record := range records {
+		row, ok := chirpRow(record, config)
+		if !ok {
+			continue
+		}
+		name, err := namer.name(record, row["Name"])
+		if err != nil {
+			return err
+		}
+		row["Name"] = namer.fit(name, "")
+		rows = append(rows, row)
+		if config.AddTalkaround && row["Duplex"] != "" {
+			ta := talkaroundRow(row)
+			ta["Name"] = namer.fit(name, " TA")
+			rows = append(rows, ta)
+		}
+	}
+	rows = append(rows, simplexRows(records, namer, config)...)
+	rows = append(rows, weatherRows(records, namer, config)...)
+	if err := assignLocations(rows, config); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		values := make([]string, len(chirpHeaders))
+		for i, header := range chirpHeaders {
+			values[i] = row[header]
+		}
+		if err := writer.Write(values); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+	}
+	return nil
+}
+
+// Maps one repeater onto CHIRP's columns, skipping records without a usable frequency
+func chirpRow(record map[string]interface{}, config *Config) (map[string]string, bool) {
+	output, ok := recordFloat(record, "Frequency")
+	if !ok || output <= 0 {
+		return nil, false
+	}
+	row := map[string]string{
+		"Name":         recordString(record, "Callsign"),
+		"Frequency":    fmt.Sprintf("%.6f", output),
+		"Offset":       "0.000000",
+		"rToneFreq":    "88.5",
+		"cToneFreq":    "88.5",
+		"DtcsCode":     "023",
+		"DtcsPolarity": "NN",
+		"RxDtcsCode":   "023",
+		"CrossMode":    "Tone->Tone",
+		"Mode":         chirpMode(record),
+		"TStep":        "5.00",
+	}
+	if input, ok := recordFloat(record, "Input Freq"); ok && input > 0 {
+		offset := input - output
+		switch {
+		case math.Abs(offset) > chirpSplitThreshold:
+			row["Duplex"] = "split"
+			row["Offset"] = fmt.Sprintf("%.6f", input)
+		case offset > 0.0005:
+			row["Duplex"] = "+"
+			row["Offset"] = fmt.Sprintf("%.6f", offset)
+		case offset < -0.0005:
+			row["Duplex"] = "-"
+			row["Offset"] = fmt.Sprintf("%.6f", -offset)
+		}
+	}
+	var comment []string
+	if location := cheatSheetLocation(record); location != "" {
+		comment = append(comment, location)
+	}
+	if note := distanceNote(record, distanceUnit(config)); note != "" {
+		comment = append(comment, note)
+	}
+	uplink, downlink := recordTones(record)
+	switch recordString(record, accessMethodField) {
+	case accessCTCSS:
+		row["rToneFreq"] = uplink.String()
+		// A listed downlink tone means the repeater can be squelched on it as well
+		switch {
+		case config.SwapRxTx:
+			// Users transmit the uplink tone on the input, so that is what a monitoring channel decodes
+			row["Tone"] = "TSQL"
+			row["cToneFreq"] = uplink.String()
+		case downlink.kind == accessDCS:
+			row["Tone"] = "Cross"
+			row["CrossMode"] = "Tone->DTCS"
+			row["RxDtcsCode"] = downlink.code
+			row["DtcsPolarity"] = "N" + chirpPolarity(downlink)
+		case downlink.kind != accessCTCSS:
+			row["Tone"] = "Tone"
+		case downlink.equal(uplink):
+			row["Tone"] = "TSQL"
+			row["cToneFreq"] = downlink.String()
+		default:
+			row["Tone"] = "Cross"
+			row["cToneFreq"] = downlink.String()
+		}
+	case accessDCS:
+		// The repeater answers with its own code unless a different downlink code is listed
+		rx := uplink
+		if downlink.kind == accessDCS && !config.SwapRxTx {
+			rx = downlink
+		}
+		row["Tone"] = "DTCS"
+		row["DtcsCode"] = uplink.code
+		row["RxDtcsCode"] = rx.code
+		row["DtcsPolarity"] = chirpPolarity(uplink) + chirpPolarity(rx)
+		if !rx.equal(uplink) {
+			row["Tone"] = "Cross"
+			row["CrossMode"] = "DTCS->DTCS"
+		}
+	case accessToneBurst:
+		// CHIRP has no tone burst setting, the operator sends it from the radio's 1750 key
+		comment = append(comment, "1750 Hz tone burst")
+	}
+	if secondary := recordString(record, dualWatchField); secondary != "" {
+		comment = append(comment, "DW "+secondary)
+	}
+	if config.AutoPower {
+		row["Power"] = powerLevel(record, config)
+	}
+	if isGMRS(config) {
+		row["Name"] = gmrsName(record)
+		comment = append(comment, gmrsComment(record)...)
+	}
+	row["Comment"] = strings.Join(comment, " - ")
+	return row, true
+}
+
+// Simplex channel on the repeater's output, for working stations directly when the repeater is down or out of range
+func talkaroundRow(row map[string]string) map[string]string {
+	ta := make(map[string]string, len(row))
+	for key, value := range row {
+		ta[key] = value
+	}
+	ta["Name"] = strings.TrimSpace(row["Name"] + " TA")
+	ta["Duplex"] = ""
+	ta["Offset"] = "0.000000"
+	ta["Comment"] = strings.TrimPrefix(row["Comment"]+" - talkaround", " - ")
+	return ta
+}
+
+// CHIRP writes DCS polarity as N for normal and R for reversed
+func chirpPolarity(t tone) string {
+	if t.inverted {
+		return "R"
+	}
+	return "N"
+}
+
+func chirpMode(record map[string]interface{}) string {
+	if recordString(record, "FM Analog") == "No" && recordString(record, "D-Star") == "Yes" {
+		return "DV"
+	}
+	if strings.EqualFold(recordString(record, "FM Bandwidth"), "Narrow") {
+		return "NFM"
+	}
+	return "FM"
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const clubMemberField = "club_member"
+
+// Reads member callsigns from a roster CSV, using its callsign column or else the first column
+func loadRoster(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening club roster: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	members := make(map[string]bool)
+	column := 0
+	first := true
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading club roster: %w", err)
+		}
+		if first {
+			first = false
+			if idx := rosterCallsignColumn(row); idx >= 0 {
+				column = idx
+				continue
+			}
+		}
+		if column < len(row) {
+			if call := normalizeCallsign(row[column]); call != "" {
+				members[call] = true
+			}
+		}
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no callsigns found in club roster %s", path)
+	}
+	return members, nil
+}
+
+func rosterCallsignColumn(header []string) int {
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "callsign", "call sign", "call":
+			return i
+		}
+	}
+	return -1
+}
+
+// Uppercases a callsign and drops portable or mobile suffixes such as /P or /M
+func normalizeCallsign(call string) string {
+	call = strings.ToUpper(strings.TrimSpace(call))
+	if base, _, ok := strings.Cut(call, "/"); ok {
+		call = base
+	}
+	return call
+}
+
+// The trustee is listed when the API has it, otherwise the repeater callsign usually belongs to the trustee or club
+func recordTrustee(record map[string]interface{}) string {
+	if trustee := recordString(record, "Trustee"); trustee != "" {
+		return normalizeCallsign(trustee)
+	}
+	return normalizeCallsign(recordString(record, "Callsign"))
+}
+
+func tagClubRepeaters(records []map[string]interface{}, members map[string]bool) {
+	for _, record := range records {
+		member := ""
+		if trustee := recordTrustee(record); members[trustee] {
+			member = trustee
+		}
+		record[clubMemberField] = member
+	}
+}
+
+const sponsorField = "Sponsor"
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// One output column from a --columns file: the record field it shows, the header it's written
+// under, and a value for records without the field. A blank field makes a constant column.
+type columnMapping struct {
+	field    string
+	column   string
+	fallback string
+}
+
+// Reads field[,column[,default]] lines in output order, a field alone keeping its name. Only the
+// listed columns are written.
+func loadColumnMap(path string) ([]columnMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening column map: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var columns []columnMapping
+	for first := true; ; first = false {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading column map: %w", err)
+		}
+		if first && strings.EqualFold(strings.TrimSpace(row[0]), "field") {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) > 3 {
+			return nil, fmt.Errorf("reading column map: line %d has more than field,column,default", line)
+		}
+		mapping := columnMapping{field: strings.TrimSpace(row[0])}
+		if len(row) > 1 {
+			mapping.column = strings.TrimSpace(row[1])
+		}
+		if len(row) > 2 {
+			mapping.fallback = row[2]
+		}
+		if mapping.column == "" {
+			mapping.column = mapping.field
+		}
+		if mapping.column == "" {
+			return nil, fmt.Errorf("reading column map: line %d names no field or column", line)
+		}
+		columns = append(columns, mapping)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns found in %s", path)
+	}
+	return columns, nil
+}
+
+func (m columnMapping) value(record map[string]interface{}) string {
+	if m.field != "" {
+		if value := recordString(record, m.field); value != "" {
+			return value
+		}
+	}
+	return m.fallback
+}
+
+// Writes records with the columns a --columns file lays out, in place of every field sorted by name
+func writeMappedCSV(writer csvRowWriter, records []map[string]interface{}, path string) error {
+	columns, err := loadColumnMap(path)
+	if err != nil {
+		return err
+	}
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.column
+	}
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("writing headers: %w", err)
+	}
+	for _, record := range records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.value(record)
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+// Subcommands, in the order they're listed by rbdl help. Without one, arguments are taken as
+// options for fetch. There's no serve command, since parseFlags exits on bad options rather than
+// returning an error a server could answer with.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"fetch", "rbdl [fetch] [options]", "Download repeaters and save them in any output format", runFetchCommand},
+		{"browse", "rbdl browse [options]", "Fetch repeaters, then sort, filter and pick which to export", runBrowseCommand},
+		{"convert", "rbdl convert input.json [options]", "Save a downloaded JSON file in another format, offline", runConvertCommand},
+		{"merge", "rbdl merge input.json input.json... [options]", "Combine saved downloads into one file without duplicates, offline", runMergeCommand},
+		{"diff", "rbdl diff old.json new.json [--json] [--ignore fields]", "Report repeaters added, removed and changed between two saved downloads", runDiffCommand},
+		{"enrich", "rbdl enrich radio.csv [saved.json] [options]", "Fill in missing tones, offsets and names in a CHIRP CSV from RepeaterBook", runEnrichCommand},
+		{"lint", "rbdl lint [saved.json] [--json] [--stale-before date] [options]", "Report listings with missing coordinates or tones, zero offsets, stale dates or shared frequencies", runLintCommand},
+		{"identify", "rbdl identify [saved.json...] --freq MHz [--tone Hz] [--near place] [options]", "List the repeaters likely behind a signal just heard, from saved, fresh or cached data", runIdentifyCommand},
+		{"get", "rbdl get state_id rptr_id [options]", "Look up a single repeater", runGetCommand},
+		{"replay", "rbdl replay [--live] transcript.json [options]", "Repeat a download recorded with --transcript", runReplayCommand},
+		{"config", "rbdl config validate|show [--effective] [options]", "Check options and show where each value comes from", runConfigCommand},
+		{"setup", "rbdl setup", "Answer a few questions to write the config file", runSetupCommand},
+		{"login", "rbdl login [email]", "Save the email to the OS keychain instead of passing it around", runLoginCommand},
+		{"logout", "rbdl logout", "Remove the saved email from the OS keychain", runLogoutCommand},
+		{"states", "rbdl states [country]", "List the states and provinces --state accepts", runStatesCommand},
+		{"countries", "rbdl countries", "List the countries --country accepts", runCountriesCommand},
+		{"presets", "rbdl presets", "List the bundles of options --preset accepts", runPresetsCommand},
+		{"version", "rbdl version [options]", "Print the rbdl version, build details and User-Agent", runVersionCommand},
+		{"help", "rbdl help", "List these commands", runHelpCommand},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func runFetchCommand(args []string) int {
+	config := parseFlags(flag.CommandLine, args)
+	if config.ShowVersion {
+		printVersion(config)
+		return 0
+	}
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	var transcript *Transcript
+	if config.Transcript != "" {
+		transcript = newTranscript(flag.CommandLine, config)
+	}
+	return runDownload(config, transcript)
+}
+
+func runPresetsCommand(args []string) int {
+	file, err := findConfigFile(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	printPresets(file)
+	return 0
+}
+
+// Options are read as for a download, so the User-Agent shown carries the configured email
+func runVersionCommand(args []string) int {
+	fs := flag.NewFlagSet("rbdl version", flag.ExitOnError)
+	printVersion(parseFlags(fs, args))
+	return 0
+}
+
+func runHelpCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl help\n")
+		return 1
+	}
+	fmt.Printf("RepeaterbookDL - Download repeater data from RepeaterBook API\n\n")
+	fmt.Printf("Commands:\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	w.Flush()
+	fmt.Printf("\nRun rbdl fetch --help for the download options, which the other commands accept where they apply.\n")
+	return 0
+}
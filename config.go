@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Every flag can be supplied through the environment, e.g. --on-air as RBDL_ON_AIR
+func envName(flagName string) string {
+	return "RBDL_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Environment values replace flag defaults, so anything given on the command line still takes precedence
+func applyEnvironment(fs *flag.FlagSet, skip map[string]bool) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || skip[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || value == "" {
+			return
+		}
+		if setErr := setFlagDefault(f, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// Sets a flag's value as its new default, so list flags are replaced rather than extended by the
+// command line
+func setFlagDefault(f *flag.Flag, value string) error {
+	set := f.Value.Set
+	if d, ok := f.Value.(interface{ SetDefault(string) error }); ok {
+		set = d.SetDefault
+	}
+	if err := set(value); err != nil {
+		return err
+	}
+	f.DefValue = value
+	return nil
+}
+
+// Finds an option's value on the command line before it is parsed, for options that decide the
+// defaults of the others
+func argValue(args []string, option string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if len(name) == len(arg) || len(arg)-len(name) > 2 {
+			continue
+		}
+		if value, ok := strings.CutPrefix(name, option+"="); ok {
+			return value
+		}
+		if name == option && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+type configIssue struct {
+	severity string
+	message  string
+}
+
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "validate" && args[0] != "show") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl config validate|show [--effective] [options]\n")
+		return 1
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("rbdl config "+action, flag.ExitOnError)
+	effective := fs.Bool("effective", false, "Include defaults and derived values when showing the configuration")
+	config := parseFlags(fs, args[1:])
+	if action == "show" {
+		printConfig(fs, config, *effective)
+		return 0
+	}
+	issues := checkConfig(fs, config)
+	printConfig(fs, config, true)
+	if len(issues) == 0 {
+		fmt.Println("\nConfiguration is valid")
+		return 0
+	}
+	fmt.Println()
+	failed := false
+	for _, issue := range issues {
+		fmt.Printf("%s: %s\n", issue.severity, issue.message)
+		if issue.severity == "error" {
+			failed = true
+		}
+	}
+	if failed {
+		return exitInvalid
+	}
+	return 0
+}
+
+// Reports where each flag's value came from: an explicit flag, the preset, the profile, its
+// environment variable, the config file, or the default
+func configSources(fs *flag.FlagSet, config *Config) map[string]string {
+	sources := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if config.presetOptions[f.Name] {
+			sources[f.Name] = "preset " + config.Preset
+		} else if config.profileOptions[f.Name] {
+			sources[f.Name] = "profile " + config.Profile
+		} else if f.Name != "effective" && os.Getenv(envName(f.Name)) != "" {
+			sources[f.Name] = "env " + envName(f.Name)
+		} else if config.fileOptions[f.Name] {
+			sources[f.Name] = "config file"
+		} else {
+			sources[f.Name] = "default"
+		}
+	})
+	fs.Visit(func(f *flag.Flag) {
+		sources[f.Name] = "flag"
+	})
+	if config.emailFromKeychain {
+		sources["email"] = "keychain"
+	}
+	if sources["format"] == "default" && fs.Lookup("output").Value.String() != "" {
+		sources["format"] = "auto-detected from --output"
+	}
+	return sources
+}
+
+// Options that say where to search or measure distances from
+var locationOptions = []string{"lat", "lon", "grid", "near", "near-me", "gps", "from"}
+
+// A location from one source replaces those from the sources it overrides rather than clashing with
+// them, so --near on the command line searches there instead of around the config file's home
+func replaceLocation(fs *flag.FlagSet, config *Config) {
+	ranks := map[string]int{"config": 1, "env": 2, "profile": 3, "preset": 4, "flag": 5}
+	sources := configSources(fs, config)
+	rank := make(map[string]int)
+	top := 0
+	for _, name := range locationOptions {
+		f := fs.Lookup(name)
+		if f == nil || f.Value.String() == "" || f.Value.String() == "false" {
+			continue
+		}
+		rank[name] = ranks[strings.Fields(sources[name])[0]]
+		top = max(top, rank[name])
+	}
+	searching := false
+	for name := range rank {
+		if rank[name] == top {
+			searching = searching || name != "from"
+			continue
+		}
+		f := fs.Lookup(name)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			f.Value.Set("false")
+		} else {
+			f.Value.Set("")
+		}
+	}
+	// The radius went with the search that was replaced, unless it came with the new one
+	if !searching && ranks[strings.Fields(sources["distance"])[0]] < top {
+		config.Distance = defaultDistance
+	}
+}
+
+func printConfig(fs *flag.FlagSet, config *Config, effective bool) {
+	sources := configSources(fs, config)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "effective" {
+			return
+		}
+		value := f.Value.String()
+		// Format is resolved after parsing, so read it back from the config
+		if f.Name == "format" {
+			value = config.Format
+		}
+		if !effective && (sources[f.Name] == "default" || value == "") {
+			return
+		}
+		fmt.Fprintf(w, "%s\t= %s\t(%s)\n", f.Name, value, sources[f.Name])
+	})
+	w.Flush()
+}
+
+func checkConfig(fs *flag.FlagSet, config *Config) []configIssue {
+	var issues []configIssue
+	if err := validateConfig(config); err != nil {
+		issues = append(issues, configIssue{"error", err.Error()})
+	}
+	sources := configSources(fs, config)
+	if config.Output != "" && sources["format"] == "flag" {
+		ext := strings.ToLower(filepath.Ext(config.Output))
+		if detected, ok := formatExtensions[ext]; ok && detected != config.Format && ext != formatExtension(config.Format) {
+			issues = append(issues, configIssue{"warning", fmt.Sprintf("--format %s conflicts with the %s extension of --output %s", config.Format, ext, config.Output)})
+		}
+	}
+	if config.County != "" && config.StateID == "" {
+		issues = append(issues, configIssue{"warning", "--county without --state matches counties of that name in every state"})
+	}
+	known := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "effective" {
+			return
+		}
+		env := envName(f.Name)
+		known[env] = true
+		if sources[f.Name] == "flag" && os.Getenv(env) != "" && os.Getenv(env) != f.Value.String() {
+			issues = append(issues, configIssue{"warning", fmt.Sprintf("--%s overrides %s", f.Name, env)})
+		}
+		if value, ok := config.file.Options[f.Name]; ok && sources[f.Name] == "flag" && value != f.Value.String() {
+			issues = append(issues, configIssue{"warning", fmt.Sprintf("--%s overrides %s from %s", f.Name, f.Name, config.file.Path)})
+		}
+	})
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "RBDL_") && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		issues = append(issues, configIssue{"warning", fmt.Sprintf("unknown environment variable %s", name)})
+	}
+	return issues
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options read from a TOML config file. Top-level keys are flag names and set their defaults,
+// [presets.NAME] tables define presets alongside the built-in ones and [profiles.NAME] tables
+// bundle options selected with --profile.
+type configFile struct {
+	Path     string
+	Options  map[string]string
+	Presets  map[string]preset
+	Profiles map[string]map[string]string
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rbdl", "config.toml")
+}
+
+// Loads the file named by --config or RBDL_CONFIG, or the default one if it exists
+func findConfigFile(args []string) (*configFile, error) {
+	path := argValue(args, "config")
+	if path == "" {
+		path = os.Getenv(envName("config"))
+	}
+	if path == "" {
+		path = defaultConfigPath()
+		if _, err := os.Stat(path); path == "" || err != nil {
+			return &configFile{}, nil
+		}
+	}
+	return loadConfigFile(path)
+}
+
+// Parses the subset of TOML a flat list of options needs: tables, key = value pairs with strings,
+// numbers, booleans and arrays of strings, and # comments
+func loadConfigFile(path string) (*configFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+	file := &configFile{Path: path, Options: make(map[string]string), Presets: make(map[string]preset), Profiles: make(map[string]map[string]string)}
+	options := file.Options
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			section := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(stripComment(text), "["), "]"))
+			kind, name, ok := strings.Cut(section, ".")
+			name = strings.Trim(name, `"`)
+			if !ok || (kind != "presets" && kind != "profiles") || name == "" {
+				return nil, fmt.Errorf("%s:%d: unknown table [%s], expected [presets.NAME] or [profiles.NAME]", path, line, section)
+			}
+			options = make(map[string]string)
+			if kind == "presets" {
+				file.Presets[name] = preset{Description: "from " + path, Options: options}
+			} else {
+				file.Profiles[name] = options
+			}
+			continue
+		}
+		key, raw, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, line)
+		}
+		key = strings.ReplaceAll(strings.TrimSpace(key), "_", "-")
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		options[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return file, nil
+}
+
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// Turns a TOML value into the text a flag would be given, joining arrays with commas
+func parseTOMLValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := closingQuote(raw)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strconv.Unquote(raw[:end+1])
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return raw[1 : end+1], nil
+	case strings.HasPrefix(raw, "["):
+		end := strings.LastIndex(raw, "]")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated array, arrays must fit on one line")
+		}
+		var items []string
+		rest := strings.TrimSpace(raw[1:end])
+		for rest != "" {
+			item := rest
+			if strings.HasPrefix(rest, `"`) {
+				if e := closingQuote(rest); e >= 0 {
+					item = rest[:e+1]
+				}
+			} else if i := strings.Index(rest, ","); i >= 0 {
+				item = rest[:i]
+			}
+			value, err := parseTOMLValue(strings.TrimSpace(item))
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+			rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest[len(item):]), ","))
+		}
+		return strings.Join(items, ","), nil
+	}
+	value := stripComment(raw)
+	if value == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	return value, nil
+}
+
+// Finds the quote ending a basic string, skipping escaped ones
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// Config file options replace flag defaults, beneath the environment, presets and the command line.
+// Returns the flags that were set.
+func applyConfigFile(fs *flag.FlagSet, file *configFile, skip map[string]bool) (map[string]bool, error) {
+	applied := make(map[string]bool)
+	for option, value := range file.Options {
+		f := fs.Lookup(option)
+		if f == nil || option == "config" || option == "effective" {
+			return nil, fmt.Errorf("%s: unknown option %s", file.Path, option)
+		}
+		if skip[option] {
+			continue
+		}
+		if err := setFlagDefault(f, value); err != nil {
+			return nil, fmt.Errorf("%s: invalid value %q for %s: %v", file.Path, value, option, err)
+		}
+		applied[option] = true
+	}
+	return applied, nil
+}
+
+// A profile's options replace those of the config file and the environment, while presets and the
+// command line still take precedence. Returns the flags that were set.
+func applyProfile(fs *flag.FlagSet, name string, file *configFile, skip map[string]bool) (map[string]bool, error) {
+	options, ok := file.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for profile := range file.Profiles {
+			names = append(names, profile)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown profile %q, define profiles as [profiles.NAME] tables in the config file", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	applied := make(map[string]bool)
+	for option, value := range options {
+		f := fs.Lookup(option)
+		if f == nil || option == "config" || option == "profile" || option == "effective" {
+			return nil, fmt.Errorf("profile %s sets unknown option %s", name, option)
+		}
+		if skip[option] {
+			continue
+		}
+		if err := setFlagDefault(f, value); err != nil {
+			return nil, fmt.Errorf("profile %s: invalid value %q for %s: %v", name, value, option, err)
+		}
+		applied[option] = true
+	}
+	return applied, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Renders a previously downloaded JSON file in another format without contacting the API
+func runConvertCommand(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl convert input.json [options]\n")
+		return 1
+	}
+	input := args[0]
+	fs := flag.NewFlagSet("rbdl convert", flag.ExitOnError)
+	config := parseFlags(fs, args[1:])
+	if err := validateOptions(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		return 1
+	}
+	if err := resolveLocation(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving location: %v\n", err)
+		return 1
+	}
+	outputFile := outputPath(config)
+	if config.Output == "" {
+		outputFile = convertedFilename(input, config.Format)
+		if config.OutputDir != "" {
+			outputFile = filepath.Join(config.OutputDir, filepath.Base(outputFile))
+		}
+		if filepath.Clean(outputFile) == filepath.Clean(input) {
+			fmt.Fprintf(os.Stderr, "Error: converting to %s would overwrite the input, give --output\n", config.Format)
+			return 1
+		}
+	}
+	if err := makeOutputDir(outputFile, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	groups, err := splitResponse(data, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if _, err := saveGroups(groups, suffixGroupPaths(groups, outputFile), config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+// The input's name with the format's extension, e.g. montana.json becomes montana_chirp.csv for CHIRP
+func convertedFilename(input, format string) string {
+	base := strings.TrimSuffix(input, filepath.Ext(input))
+	if format == "chirp" || format == "garmin" {
+		base += "_" + format
+	}
+	return base + formatExtension(format)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Derived field holding the Maidenhead locator of a repeater, with --coords grid
+const gridField = "grid"
+
+const (
+	coordsDecimal = "decimal"
+	coordsDMS     = "dms"
+	coordsGrid    = "grid"
+)
+
+func validateCoords(config *Config) error {
+	switch config.Coords {
+	case coordsDecimal, coordsDMS, coordsGrid:
+	default:
+		return fmt.Errorf("--coords must be one of: decimal, dms or grid")
+	}
+	if config.Coords != coordsDecimal {
+		switch config.Format {
+		case "json", "csv", "msgpack", "template":
+		default:
+			return fmt.Errorf("--coords %s is only supported for json, csv, msgpack and template output", config.Coords)
+		}
+	}
+	return nil
+}
+
+// Rewrites Lat and Long as degrees, minutes and seconds, or adds the grid square, for the data
+// formats. Values that aren't decimal degrees, such as ones already converted in a file being
+// appended to, are left as they are.
+func formatCoordinates(records []map[string]interface{}, config *Config) {
+	if config.Coords == "" || config.Coords == coordsDecimal {
+		return
+	}
+	degree := "°"
+	if config.Encoding == "ascii" {
+		degree = " "
+	}
+	for _, record := range records {
+		lat, latOK := recordFloat(record, "Lat")
+		lon, lonOK := recordFloat(record, "Long")
+		if !latOK || !lonOK || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			continue
+		}
+		if config.Coords == coordsGrid {
+			record[gridField] = latLonToGrid(lat, lon)
+			continue
+		}
+		record["Lat"] = formatDMS(lat, "N", "S", degree)
+		record["Long"] = formatDMS(lon, "E", "W", degree)
+	}
+}
+
+// Formats a coordinate such as 45.677 as 45°40'37.2"N
+func formatDMS(value float64, positive, negative, degree string) string {
+	hemisphere := positive
+	if value < 0 {
+		hemisphere = negative
+		value = -value
+	}
+	// Work in tenths of a second so rounding carries into the minutes and degrees
+	tenths := int(math.Round(value * 36000))
+	degrees := tenths / 36000
+	minutes := tenths % 36000 / 600
+	seconds := float64(tenths%600) / 10
+	return fmt.Sprintf("%d%s%02d'%04.1f\"%s", degrees, degree, minutes, seconds, hemisphere)
+}
+
+// Converts coordinates to the 6-character Maidenhead locator containing them, e.g. DN45lq
+func latLonToGrid(lat, lon float64) string {
+	lat = math.Min(lat+90, 180-1e-9)
+	lon = math.Min(lon+180, 360-1e-9)
+	var b strings.Builder
+	latSize, lonSize := 180.0, 360.0
+	for _, div := range gridDivisions[:3] {
+		latSize /= float64(div.count)
+		lonSize /= float64(div.count)
+		lonIndex := int(lon / lonSize)
+		latIndex := int(lat / latSize)
+		b.WriteByte(div.first + byte(lonIndex))
+		b.WriteByte(div.first + byte(latIndex))
+		lon -= float64(lonIndex) * lonSize
+		lat -= float64(latIndex) * latSize
+	}
+	grid := b.String()
+	return grid[:4] + strings.ToLower(grid[4:])
+}
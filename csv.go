@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var csvDelimiters = map[string]rune{
+	"comma":     ',',
+	"semicolon": ';',
+	"tab":       '\t',
+	"pipe":      '|',
+}
+
+func csvDelimiter(name string) (rune, error) {
+	if r, ok := csvDelimiters[name]; ok {
+		return r, nil
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		if r != '"' && r != '\r' && r != '\n' {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("CSV delimiter must be comma, semicolon, tab, pipe or a single character")
+}
+
+type csvRowWriter interface {
+	Write(record []string) error
+	Flush()
+}
+
+// Builds a writer for the configured CSV dialect, writing a byte order mark first if requested
+func newCSVWriter(w io.Writer, config *Config) (csvRowWriter, error) {
+	delimiter, err := csvDelimiter(config.CSVDelimiter)
+	if err != nil {
+		return nil, err
+	}
+	if config.CSVBOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return nil, err
+		}
+	}
+	if config.HeaderComment {
+		lineEnd := "\n"
+		if config.CSVCRLF {
+			lineEnd = "\r\n"
+		}
+		for _, line := range headerComments(config) {
+			if _, err := io.WriteString(w, "# "+line+lineEnd); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if config.CSVQuote == "all" {
+		return &quoteAllWriter{w: w, delimiter: delimiter, crlf: config.CSVCRLF}, nil
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	writer.UseCRLF = config.CSVCRLF
+	return writer, nil
+}
+
+// Describes where a file came from, for readers of shared exports
+func headerComments(config *Config) []string {
+	lines := []string{fmt.Sprintf("Generated by rbdl %s on %s", version, time.Now().Format(time.RFC3339))}
+	for _, query := range expandQueries(config) {
+		lines = append(lines, "Query: "+buildQueryURL(query))
+	}
+	return append(lines, "Data: RepeaterBook.com")
+}
+
+// encoding/csv only quotes fields when needed, some CPS importers expect every field quoted
+type quoteAllWriter struct {
+	w         io.Writer
+	delimiter rune
+	crlf      bool
+	err       error
+}
+
+func (q *quoteAllWriter) Write(record []string) error {
+	if q.err != nil {
+		return q.err
+	}
+	var sb strings.Builder
+	for i, field := range record {
+		if i > 0 {
+			sb.WriteRune(q.delimiter)
+		}
+		sb.WriteByte('"')
+		sb.WriteString(strings.ReplaceAll(field, `"`, `""`))
+		sb.WriteByte('"')
+	}
+	if q.crlf {
+		sb.WriteString("\r\n")
+	} else {
+		sb.WriteByte('\n')
+	}
+	_, q.err = io.WriteString(q.w, sb.String())
+	return q.err
+}
+
+func (q *quoteAllWriter) Flush() {}
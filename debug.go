@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cartertemm/rbdl/cache"
+)
+
+// Logs a line to stderr when --verbose or --debug is set, hiding the email with --redact-email so
+// logs can be shared in bug reports. Lines are prefixed like curl -v: > for requests, < for responses.
+func (config *Config) logf(format string, args ...interface{}) {
+	if !config.Verbose && !config.Debug {
+		return
+	}
+	line := fmt.Sprintf(format, args...)
+	if config.RedactEmail && config.Email != "" {
+		line = strings.ReplaceAll(line, config.Email, "<email>")
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
+
+func logHeaders(config *Config, prefix string, header http.Header) {
+	if !config.Debug {
+		return
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			config.logf("%s %s: %s", prefix, name, value)
+		}
+	}
+}
+
+func logRequest(config *Config, req *http.Request) {
+	config.logf("> %s %s", req.Method, req.URL)
+	logHeaders(config, ">", req.Header)
+}
+
+func logResponse(config *Config, resp *http.Response, elapsed time.Duration) {
+	line := fmt.Sprintf("< %s in %s", resp.Status, elapsed.Round(time.Millisecond))
+	if cached := resp.Header.Get(cache.StatusHeader); cached != "" {
+		line += " (cache " + cached + ")"
+	}
+	config.logf("%s", line)
+	logHeaders(config, "<", resp.Header)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type fieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// A repeater in both downloads whose listing changed, with the old and new value of each field
+type repeaterChange struct {
+	Repeater map[string]interface{} `json:"repeater"`
+	Changes  map[string]fieldChange `json:"changes"`
+}
+
+type downloadDiff struct {
+	Added   []map[string]interface{} `json:"added"`
+	Removed []map[string]interface{} `json:"removed"`
+	Changed []repeaterChange         `json:"changed"`
+}
+
+// Reports repeaters added, removed and changed between two saved downloads
+func runDiffCommand(args []string) int {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl diff old.json new.json [--json] [--ignore fields]\n")
+		return 1
+	}
+	fs := flag.NewFlagSet("rbdl diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the differences as JSON")
+	ignore := fs.String("ignore", "", "Comma-separated fields whose changes aren't reported (e.g., \"Last Update\")")
+	fs.Parse(args[2:])
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl diff old.json new.json [--json] [--ignore fields]\n")
+		return 1
+	}
+	// Saved CSV files use the default delimiter
+	config := &Config{CSVDelimiter: "comma"}
+	var sides [2][]map[string]interface{}
+	for i, path := range args[:2] {
+		data, err := readSavedDownload(path, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		var response struct {
+			Results []map[string]interface{} `json:"results"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s isn't a saved download: %v\n", path, err)
+			return 1
+		}
+		sides[i] = response.Results
+	}
+	ignored := make(map[string]bool)
+	for _, field := range strings.Split(*ignore, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			ignored[strings.ToLower(field)] = true
+		}
+	}
+	diff := diffRecords(sides[0], sides[1], ignored)
+	if *asJSON {
+		encoded, err := json.MarshalIndent(diff, "", "\t")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(encoded))
+		return 0
+	}
+	printDiff(os.Stdout, diff)
+	return 0
+}
+
+// Pairs repeaters up by recordKeys. Derived fields, named in lowercase, are computed by rbdl rather
+// than listed, so only RepeaterBook's own fields are compared.
+func diffRecords(before, after []map[string]interface{}, ignored map[string]bool) downloadDiff {
+	diff := downloadDiff{
+		Added:   []map[string]interface{}{},
+		Removed: []map[string]interface{}{},
+		Changed: []repeaterChange{},
+	}
+	index := make(map[string]int)
+	for i, record := range before {
+		for _, key := range recordKeys(record) {
+			if _, ok := index[key]; !ok {
+				index[key] = i
+			}
+		}
+	}
+	matched := make(map[int]bool)
+	for _, record := range after {
+		position := -1
+		for _, key := range recordKeys(record) {
+			if i, ok := index[key]; ok && !matched[i] {
+				position = i
+				break
+			}
+		}
+		if position < 0 {
+			diff.Added = append(diff.Added, record)
+			continue
+		}
+		matched[position] = true
+		if changes := fieldChanges(before[position], record, ignored); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, repeaterChange{Repeater: record, Changes: changes})
+		}
+	}
+	for i, record := range before {
+		if !matched[i] {
+			diff.Removed = append(diff.Removed, record)
+		}
+	}
+	return diff
+}
+
+func fieldChanges(was, now map[string]interface{}, ignored map[string]bool) map[string]fieldChange {
+	changes := make(map[string]fieldChange)
+	fields := make(map[string]bool)
+	for field := range was {
+		fields[field] = true
+	}
+	for field := range now {
+		fields[field] = true
+	}
+	for field := range fields {
+		if field == strings.ToLower(field) || ignored[strings.ToLower(field)] {
+			continue
+		}
+		old, updated := recordString(was, field), recordString(now, field)
+		if old != updated {
+			changes[field] = fieldChange{Old: old, New: updated}
+		}
+	}
+	return changes
+}
+
+// One line naming a repeater, e.g. W7YB 146.8800 Bozeman, Montana
+func diffLabel(record map[string]interface{}) string {
+	parts := []string{recordString(record, "Callsign")}
+	if freq, ok := recordFloat(record, "Frequency"); ok {
+		parts = append(parts, fmt.Sprintf("%.4f", freq))
+	}
+	if location := cheatSheetLocation(record); location != "" {
+		parts = append(parts, location)
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+func printDiff(w io.Writer, diff downloadDiff) {
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(w, "Added (%d):\n", len(diff.Added))
+		for _, record := range diff.Added {
+			fmt.Fprintf(w, "  + %s\n", diffLabel(record))
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Fprintf(w, "Removed (%d):\n", len(diff.Removed))
+		for _, record := range diff.Removed {
+			fmt.Fprintf(w, "  - %s\n", diffLabel(record))
+		}
+	}
+	if len(diff.Changed) > 0 {
+		fmt.Fprintf(w, "Changed (%d):\n", len(diff.Changed))
+		for _, change := range diff.Changed {
+			fmt.Fprintf(w, "  ~ %s\n", diffLabel(change.Repeater))
+			fields := make([]string, 0, len(change.Changes))
+			for field := range change.Changes {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				value := change.Changes[field]
+				fmt.Fprintf(w, "      %s: %s -> %s\n", field, diffValue(value.Old), diffValue(value.New))
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+}
+
+func diffValue(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	nxdnRANField = "NXDN RAN"
+	p25NACField  = "P25 NAC"
+)
+
+// NACs are 12-bit hex values written as 293, 0x293 or $293, so compare them as numbers
+func parseNAC(s string) (int64, bool) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "$")
+	n, err := strconv.ParseInt(s, 16, 64)
+	if err != nil || n < 0 || n > 0xFFF {
+		return 0, false
+	}
+	return n, true
+}
+
+func validateDigitalFilters(config *Config) error {
+	if config.NXDNRAN != "" {
+		if ran, err := strconv.Atoi(config.NXDNRAN); err != nil || ran < 0 || ran > 63 {
+			return fmt.Errorf("NXDN RAN must be a number from 0 to 63")
+		}
+	}
+	if config.P25NAC != "" {
+		if _, ok := parseNAC(config.P25NAC); !ok {
+			return fmt.Errorf("P25 NAC must be a hex value from 000 to FFF, e.g. 293")
+		}
+	}
+	return nil
+}
+
+func filterDigital(records []map[string]interface{}, config *Config) []map[string]interface{} {
+	if config.NXDNRAN != "" {
+		want, _ := strconv.Atoi(config.NXDNRAN)
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			ran, ok := recordFloat(record, nxdnRANField)
+			return recordString(record, "NXDN") == "Yes" && ok && int(ran) == want
+		})
+	}
+	if config.P25NAC != "" {
+		want, _ := parseNAC(config.P25NAC)
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			nac, ok := parseNAC(recordString(record, p25NACField))
+			return recordString(record, "APCO P25") == "Yes" && ok && nac == want
+		})
+	}
+	return records
+}
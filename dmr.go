@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	dmrColorCodeField = "DMR Color Code"
+	dmrNetworkField   = "DMR Network"
+	notesField        = "Notes"
+)
+
+// Networks and talkgroups have no field of their own on most listings, trustees put them in the notes
+func dmrNetworkMatches(record map[string]interface{}, network string) bool {
+	network = strings.ToLower(network)
+	if value := recordString(record, dmrNetworkField); value != "" {
+		return strings.Contains(strings.ToLower(value), network)
+	}
+	return strings.Contains(strings.ToLower(recordString(record, notesField)), network)
+}
+
+// Matches the talkgroup as a whole number, so 310 doesn't match a listing of 3100
+func talkgroupPattern(talkgroup string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|\D)` + regexp.QuoteMeta(talkgroup) + `(\D|$)`)
+}
+
+func validateDMRFilters(config *Config) error {
+	if config.ColorCode != "" {
+		if cc, err := strconv.Atoi(config.ColorCode); err != nil || cc < 0 || cc > 15 {
+			return fmt.Errorf("color code must be a number from 0 to 15")
+		}
+	}
+	if config.Talkgroup != "" {
+		if tg, err := strconv.Atoi(config.Talkgroup); err != nil || tg <= 0 {
+			return fmt.Errorf("talkgroup must be a positive number, e.g. 3100")
+		}
+	}
+	return nil
+}
+
+func filterDMR(records []map[string]interface{}, config *Config) []map[string]interface{} {
+	if config.DMRNetwork == "" && config.ColorCode == "" && config.Talkgroup == "" {
+		return records
+	}
+	talkgroup := talkgroupPattern(config.Talkgroup)
+	return filterRecords(records, func(record map[string]interface{}) bool {
+		if recordString(record, "DMR") != "Yes" {
+			return false
+		}
+		if config.ColorCode != "" {
+			cc, ok := recordFloat(record, dmrColorCodeField)
+			want, _ := strconv.Atoi(config.ColorCode)
+			if !ok || int(cc) != want {
+				return false
+			}
+		}
+		if config.DMRNetwork != "" && !dmrNetworkMatches(record, config.DMRNetwork) {
+			return false
+		}
+		if config.Talkgroup != "" && !talkgroup.MatchString(recordString(record, notesField)) {
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Describes the requests and files a download would make, without making them. Location lookups
+// are requests too, so --near, --near-me and --gps are left unresolved.
+func printDryRun(out io.Writer, config *Config) int {
+	queries := expandQueries(config)
+	for i, query := range queries {
+		req, err := newAPIRequest(query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(queries) > 1 {
+			fmt.Fprintf(out, "Request %d of %d:\n", i+1, len(queries))
+		}
+		fmt.Fprintf(out, "%s %s\n", req.Method, req.URL)
+		names := make([]string, 0, len(req.Header))
+		for name := range req.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := strings.Join(req.Header[name], ", ")
+			if config.RedactEmail && config.Email != "" {
+				value = strings.ReplaceAll(value, config.Email, "<email>")
+			}
+			fmt.Fprintf(out, "%s: %s\n", name, value)
+		}
+		fmt.Fprintln(out)
+	}
+	if len(queries) > 1 && config.Throttle > 0 {
+		fmt.Fprintf(out, "Requests are %s apart.\n", config.Throttle)
+	}
+	fmt.Fprintf(out, "Responses with %d results are split into further requests by state or mode.\n", resultCap)
+	if config.CacheTTL > 0 {
+		fmt.Fprintf(out, "Responses younger than %s are served from %s.\n", config.CacheTTL, config.CacheDir)
+	}
+	if config.Near != "" || config.NearMe || config.GPS {
+		fmt.Fprintf(out, "The search location is looked up when the download runs.\n")
+	} else if _, _, ok := parseReference(config.From); config.From != "" && !ok {
+		fmt.Fprintf(out, "The --from location is looked up when the download runs.\n")
+	}
+	if config.Preview > 0 && config.Output == "" {
+		fmt.Fprintf(out, "\nThe first %d results would be previewed, nothing saved.\n", config.Preview)
+		return 0
+	}
+	outputFile := outputPath(config)
+	if config.SplitBy != "" {
+		outputFile = groupPaths([]outputGroup{{name: "<" + config.SplitBy + ">"}}, config)[0]
+	}
+	if config.ArchiveLayout != "" {
+		outputFile = archiveLocation(outputFile, config.ArchiveLayout, time.Now())
+	}
+	fmt.Fprintf(out, "\nOutput: %s (%s)\n", outputFile, config.Format)
+	if config.SplitBy != "" {
+		fmt.Fprintf(out, "A file is written for each %s in the results.\n", config.SplitBy)
+	}
+	if config.Keep > 0 {
+		fmt.Fprintf(out, "Copies matching %s beyond the newest %d would be deleted.\n", rotationPattern(config), config.Keep)
+	}
+	if config.Transcript != "" {
+		fmt.Fprintf(out, "Transcript: %s\n", config.Transcript)
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Windows-1252 differs from Latin-1 only in 0x80-0x9F, where it places typographic characters
+var windows1252High = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// Latin-9 replaces eight Latin-1 symbols, most notably adding the euro sign
+var iso885915Replaced = map[rune]byte{
+	'€': 0xa4, 'Š': 0xa6, 'š': 0xa8, 'Ž': 0xb4, 'ž': 0xb8, 'Œ': 0xbc, 'œ': 0xbd, 'Ÿ': 0xbe,
+}
+
+// ASCII approximations of accented Latin-1 letters, indexed from U+00C0
+const asciiFold = "AAAAAAACEEEEIIIIDNOOOOOxOUUUUYTsaaaaaaaceeeeiiiidnooooo/ouuuuyty"
+
+const unmappable = '?'
+
+var encodings = map[string]func(rune) byte{
+	"windows-1252": encodeWindows1252,
+	"cp1252":       encodeWindows1252,
+	"iso-8859-1":   encodeLatin1,
+	"latin1":       encodeLatin1,
+	"iso-8859-15":  encodeLatin9,
+	"latin9":       encodeLatin9,
+	"ascii":        encodeASCII,
+}
+
+func isEncoding(name string) bool {
+	if name == "utf-8" {
+		return true
+	}
+	_, ok := encodings[name]
+	return ok
+}
+
+func encodeLatin1(r rune) byte {
+	if r < 0x80 || (r >= 0xa0 && r <= 0xff) {
+		return byte(r)
+	}
+	return unmappable
+}
+
+func encodeWindows1252(r rune) byte {
+	if b, ok := windows1252High[r]; ok {
+		return b
+	}
+	return encodeLatin1(r)
+}
+
+func encodeLatin9(r rune) byte {
+	if b, ok := iso885915Replaced[r]; ok {
+		return b
+	}
+	for _, b := range iso885915Replaced {
+		if rune(b) == r {
+			return unmappable
+		}
+	}
+	return encodeLatin1(r)
+}
+
+func encodeASCII(r rune) byte {
+	if r < 0x80 {
+		return byte(r)
+	}
+	if r >= 0xc0 && r <= 0xff {
+		return asciiFold[r-0xc0]
+	}
+	return unmappable
+}
+
+// Wraps w so UTF-8 text is transcoded to a single-byte encoding, or returns w unchanged for UTF-8
+func encodedWriter(w io.Writer, name string) io.Writer {
+	encode, ok := encodings[name]
+	if !ok {
+		return w
+	}
+	return &encodingWriter{w: w, encode: encode}
+}
+
+type encodingWriter struct {
+	w      io.Writer
+	encode func(rune) byte
+	// Buffered writers can split a multi-byte character across calls
+	pending []byte
+}
+
+func (e *encodingWriter) Write(p []byte) (int, error) {
+	data := append(e.pending, p...)
+	out := make([]byte, 0, len(data))
+	for len(data) > 0 && utf8.FullRune(data) {
+		r, size := utf8.DecodeRune(data)
+		out = append(out, e.encode(r))
+		data = data[size:]
+	}
+	e.pending = append([]byte(nil), data...)
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
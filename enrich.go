@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CHIRP columns describing a channel's tone, filled in together from the repeater's listing
+var chirpToneColumns = []string{"Tone", "rToneFreq", "cToneFreq", "DtcsCode", "DtcsPolarity", "RxDtcsCode", "CrossMode"}
+
+// Channel names that are nothing but a callsign, which are replaced when the repeater's callsign changed
+var callsignName = regexp.MustCompile(`^(?i)[A-Z0-9]{1,3}[0-9][A-Z]{1,4}$`)
+
+// Fills in missing tones, offsets and outdated names in a CHIRP CSV from RepeaterBook data, leaving
+// the memories in place and every other column as it was
+func runEnrichCommand(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl enrich radio.csv [saved.json] [options]\n")
+		return 1
+	}
+	input := args[0]
+	args = args[1:]
+	saved := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		saved = args[0]
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("rbdl enrich", flag.ExitOnError)
+	config := parseFlags(fs, args)
+	// Rows are written back in CHIRP's layout whatever --format says
+	config.Format = "chirp"
+	check := validateConfig
+	if saved != "" {
+		check = validateOptions
+	}
+	if err := check(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	raw, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		return 1
+	}
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(raw, []byte("\uFEFF")))).ReadAll()
+	if err != nil || len(rows) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s isn't a CHIRP CSV: %v\n", input, err)
+		return 1
+	}
+	var data []byte
+	if saved != "" {
+		data, err = readSavedDownload(saved, config)
+	} else {
+		config.StateID, config.Country, _ = resolveStateIDs(config.StateID, config.Country)
+		if err = resolveLocation(config); err == nil {
+			data, err = fetchRepeaterData(config)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	namer, err := newChannelNamer(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	report, err := enrichRows(rows, records, namer, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	outputFile := config.Output
+	if outputFile == "" {
+		outputFile = strings.TrimSuffix(input, ".csv") + "_enriched.csv"
+	}
+	if err := checkOverwrite(outputFile, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	file, err := os.Create(outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating file: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+	writer := csv.NewWriter(encodedWriter(file, config.Encoding))
+	if err := writer.WriteAll(rows); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		return 1
+	}
+	if !config.Quiet {
+		fmt.Printf("Matched %d of %d channels: %d tones, %d offsets and %d names filled in\n", report.matched, len(rows)-1, report.tones, report.offsets, report.names)
+		for _, line := range report.ambiguous {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Printf("Successfully saved data to: %s\n", outputFile)
+	}
+	return 0
+}
+
+type enrichReport struct {
+	matched, tones, offsets, names int
+	ambiguous                      []string
+}
+
+// Updates the rows in place. A channel matches the repeater on its frequency whose callsign is in
+// the channel's name, or the only repeater on its frequency when none is.
+func enrichRows(rows [][]string, records []map[string]interface{}, namer *channelNamer, config *Config) (*enrichReport, error) {
+	columns := make(map[string]int)
+	for i, header := range rows[0] {
+		columns[header] = i
+	}
+	for _, required := range []string{"Name", "Frequency", "Duplex", "Offset", "Tone"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the CHIRP CSV has no %s column", required)
+		}
+	}
+	get := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	set := func(row []string, column, value string) {
+		if i, ok := columns[column]; ok && i < len(row) {
+			row[i] = value
+		}
+	}
+	report := &enrichReport{}
+	for _, row := range rows[1:] {
+		freq, err := strconv.ParseFloat(strings.TrimSpace(get(row, "Frequency")), 64)
+		if err != nil || freq <= 0 {
+			continue
+		}
+		name := get(row, "Name")
+		// Talkaround channels are simplex on purpose
+		if strings.HasSuffix(name, " TA") {
+			continue
+		}
+		var candidates []map[string]interface{}
+		var match map[string]interface{}
+		for _, record := range records {
+			output, ok := recordFloat(record, "Frequency")
+			if !ok || math.Abs(output-freq) > 0.0005 {
+				continue
+			}
+			candidates = append(candidates, record)
+			call := recordString(record, "Callsign")
+			if call != "" && strings.Contains(strings.ToUpper(name), strings.ToUpper(call)) {
+				match = record
+			}
+		}
+		if match == nil && len(candidates) == 1 {
+			match = candidates[0]
+		}
+		if match == nil {
+			if len(candidates) > 1 {
+				report.ambiguous = append(report.ambiguous, fmt.Sprintf("%s on %.4f: %d repeaters share the frequency, put the callsign in the name to pick one", name, freq, len(candidates)))
+			}
+			continue
+		}
+		listed, ok := chirpRow(match, config)
+		if !ok {
+			continue
+		}
+		report.matched++
+		if get(row, "Tone") == "" && listed["Tone"] != "" {
+			for _, column := range chirpToneColumns {
+				set(row, column, listed[column])
+			}
+			report.tones++
+		}
+		if get(row, "Duplex") == "" && listed["Duplex"] != "" {
+			set(row, "Duplex", listed["Duplex"])
+			set(row, "Offset", listed["Offset"])
+			report.offsets++
+		}
+		call := recordString(match, "Callsign")
+		if strings.TrimSpace(name) == "" || callsignName.MatchString(name) && !strings.EqualFold(name, call) {
+			renamed, err := namer.name(match, listed["Name"])
+			if err != nil {
+				return nil, err
+			}
+			set(row, "Name", namer.fit(renamed, ""))
+			report.names++
+		}
+	}
+	return report, nil
+}
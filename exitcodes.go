@@ -0,0 +1,26 @@
+package main
+
+import "errors"
+
+// Exit codes scripts can branch on. Invalid flags exit with 2 as well, from the flag package.
+const (
+	exitError       = 1
+	exitInvalid     = 2
+	exitRateLimited = 3
+	exitNoResults   = 4
+)
+
+var (
+	errRateLimited = errors.New("rate limit exceeded (429): too many requests. Wait 10-60 seconds before retrying")
+	errNoResults   = errors.New("no results")
+)
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errRateLimited):
+		return exitRateLimited
+	case errors.Is(err, errNoResults):
+		return exitNoResults
+	}
+	return exitError
+}
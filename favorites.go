@@ -0,0 +1,31 @@
+package main
+
+// Derived field marking repeaters on the --favorites list
+const favoriteField = "favorite"
+
+// Marks the --favorites repeaters and moves them to the front, keeping their order and the order of
+// the rest, so they come first in every output and in codeplug memories
+func prioritizeFavorites(records []map[string]interface{}, config *Config) ([]map[string]interface{}, error) {
+	if config.Favorites == "" {
+		return records, nil
+	}
+	list, err := loadRepeaterList(config.Favorites, "--favorites")
+	if err != nil {
+		return nil, err
+	}
+	favorites := make([]map[string]interface{}, 0, len(records))
+	var rest []map[string]interface{}
+	for _, record := range records {
+		if list.matches(record) {
+			record[favoriteField] = "yes"
+			favorites = append(favorites, record)
+		} else {
+			rest = append(rest, record)
+		}
+	}
+	return append(favorites, rest...), nil
+}
+
+func isFavorite(record map[string]interface{}) bool {
+	return recordString(record, favoriteField) == "yes"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Names downloads get without --output or --filename, e.g. repeaterbook_state_30_mode_DMR_20250108_143022.json
+const defaultFilenameTemplate = "repeaterbook_{query}_{date}_{time}.{ext}"
+
+var filenamePlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// Empty placeholders and the separators around them, so a blank {query} leaves repeaterbook_20250108
+// rather than repeaterbook__20250108
+var emptyPlaceholders = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`^[_-]*\x00[_-]*`), ""},
+	{regexp.MustCompile(`[_-]*\x00[_-]*(\.|$)`), "$1"},
+	{regexp.MustCompile(`([_-]?)\x00[_-]*`), "$1"},
+}
+
+// Values for a --filename template's placeholders
+func filenameFields(config *Config, t time.Time) map[string]string {
+	fields := map[string]string{
+		"state":   strings.ReplaceAll(config.StateID, ",", "-"),
+		"county":  strings.ReplaceAll(countyName(config.County), " ", "-"),
+		"country": strings.ReplaceAll(strings.ReplaceAll(config.Country, ",", "-"), " ", "-"),
+		"mode":    queryMode(config),
+		"band":    strings.ReplaceAll(config.Band, ",", "-"),
+		"freq":    config.Frequency,
+		"grid":    config.Grid,
+		"date":    t.Format("20060102"),
+		"time":    t.Format("150405"),
+		"format":  config.Format,
+		"ext":     strings.TrimPrefix(formatExtension(config.Format), "."),
+		"group":   "",
+	}
+	if fields["freq"] == "" && (config.FreqMin != "" || config.FreqMax != "") {
+		fields["freq"] = config.FreqMin + "-" + config.FreqMax
+	}
+	// Every search parameter that was given, labelled, for names that describe the search
+	var parts []string
+	for _, part := range []struct{ label, value string }{
+		{"state", fields["state"]},
+		{"county", fields["county"]},
+		{"country", strings.ReplaceAll(config.Country, ",", "-")},
+		{"mode", fields["mode"]},
+		{"freq", fields["freq"]},
+		{"band", fields["band"]},
+		{"grid", fields["grid"]},
+	} {
+		if part.value != "" {
+			parts = append(parts, part.label+"_"+part.value)
+		}
+	}
+	if config.Grid == "" {
+		if _, _, ok := config.location(); ok {
+			parts = append(parts, "near_"+config.Lat+"_"+config.Lon)
+		}
+	}
+	fields["query"] = strings.Join(parts, "_")
+	return fields
+}
+
+func validateFilenameTemplate(tmpl string) error {
+	fields := filenameFields(&Config{}, time.Now())
+	for _, match := range filenamePlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := fields[match[1]]; !ok {
+			return fmt.Errorf("unknown placeholder {%s} in --filename", match[1])
+		}
+	}
+	if strings.Contains(tmpl, "..") {
+		return fmt.Errorf("--filename can't point outside the output directory")
+	}
+	return nil
+}
+
+// Names a download from the --filename template, beneath --output-dir. Templates without {ext}
+// have the format's extension added.
+func generateFilename(config *Config) string {
+	return expandFilename(config, filenameFields(config, time.Now()))
+}
+
+func expandFilename(config *Config, fields map[string]string) string {
+	tmpl := config.FilenameTemplate
+	if tmpl == "" {
+		tmpl = defaultFilenameTemplate
+	}
+	name := filenamePlaceholder.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		if value := fields[strings.Trim(placeholder, "{}")]; value != "" {
+			return value
+		}
+		return "\x00"
+	})
+	for strings.Contains(name, "\x00") {
+		for _, empty := range emptyPlaceholders {
+			name = empty.pattern.ReplaceAllString(name, empty.replace)
+		}
+	}
+	if !strings.Contains(tmpl, "{ext}") {
+		name += formatExtension(config.Format)
+	}
+	return filepath.Join(config.OutputDir, name)
+}
+
+// The --output path, beneath --output-dir when relative, or else a generated name
+func outputPath(config *Config) string {
+	if config.Output == "" {
+		return generateFilename(config)
+	}
+	path := config.Output
+	if config.Keep > 0 {
+		path = timestampedPath(path, time.Now().Format(rotationTimestamp))
+	}
+	if config.OutputDir != "" && !filepath.IsAbs(path) {
+		return filepath.Join(config.OutputDir, path)
+	}
+	return path
+}
+
+// Creates the directories --output-dir and --filename name, which scheduled jobs may not have made yet
+func makeOutputDir(path string, config *Config) error {
+	if config.OutputDir == "" && !strings.ContainsAny(config.FilenameTemplate, `/\`) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	return nil
+}
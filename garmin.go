@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Writes a headerless Longitude,Latitude,Name,Description file in the layout Garmin POI Loader imports
+func saveToGarmin(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w to write", errNoResults)
+	}
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(encodedWriter(file, config.Encoding))
+	defer writer.Flush()
+	written := 0
+	for _, record := range records {
+		lat := recordString(record, "Lat")
+		lon := recordString(record, "Long")
+		if lat == "" || lon == "" {
+			continue
+		}
+		name := recordString(record, "Callsign")
+		if isGMRS(config) {
+			name = gmrsName(record)
+		}
+		if err := writer.Write([]string{lon, lat, name, garminDescription(record, config)}); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+		written++
+	}
+	if written == 0 {
+		return fmt.Errorf("no repeaters with coordinates to write")
+	}
+	return nil
+}
+
+func garminDescription(record map[string]interface{}, config *Config) string {
+	parts := []string{recordString(record, "Frequency")}
+	if offset := cheatSheetOffset(record); offset != "" {
+		parts = append(parts, offset)
+	}
+	if input := recordString(record, inputFrequencyField); input != "" {
+		parts = append(parts, "in "+input)
+	}
+	if pl := recordString(record, uplinkToneField); pl != "" {
+		parts = append(parts, pl)
+	}
+	if location := cheatSheetLocation(record); location != "" {
+		parts = append(parts, location)
+	}
+	if note := distanceNote(record, distanceUnit(config)); note != "" {
+		parts = append(parts, note)
+	}
+	description := strings.Join(parts, " ")
+	if isGMRS(config) {
+		if notes := gmrsComment(record); len(notes) > 0 {
+			description += " - " + strings.Join(notes, ", ")
+		}
+	}
+	return description
+}
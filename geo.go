@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerMile     = 1.609344
+)
+
+const defaultDistance = "50mi"
+
+// Returns the configured search center, if any
+func (config *Config) location() (float64, float64, bool) {
+	if config.Grid != "" {
+		lat, lon, err := gridToLatLon(config.Grid)
+		return lat, lon, err == nil
+	}
+	if config.Lat == "" || config.Lon == "" {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(config.Lat, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(config.Lon, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+func validateLocation(config *Config) error {
+	if config.GPS {
+		if config.NearMe || config.Near != "" || config.Grid != "" || config.Lat != "" || config.Lon != "" {
+			return fmt.Errorf("--gps can't be combined with --near-me, --near, --grid, --lat or --lon")
+		}
+		_, err := parseDistance(config.Distance)
+		return err
+	}
+	if config.NearMe {
+		if config.Near != "" || config.Grid != "" || config.Lat != "" || config.Lon != "" {
+			return fmt.Errorf("--near-me can't be combined with --near, --grid, --lat or --lon")
+		}
+		_, err := parseDistance(config.Distance)
+		return err
+	}
+	if config.Near != "" {
+		if config.Grid != "" || config.Lat != "" || config.Lon != "" {
+			return fmt.Errorf("--near can't be combined with --grid, --lat or --lon")
+		}
+		_, err := parseDistance(config.Distance)
+		return err
+	}
+	if config.Grid != "" {
+		if config.Lat != "" || config.Lon != "" {
+			return fmt.Errorf("--grid can't be combined with --lat and --lon")
+		}
+		if _, _, err := gridToLatLon(config.Grid); err != nil {
+			return err
+		}
+		_, err := parseDistance(config.Distance)
+		return err
+	}
+	if config.Lat == "" && config.Lon == "" {
+		if config.Distance != defaultDistance {
+			return fmt.Errorf("--distance requires a location (--lat and --lon)")
+		}
+		return nil
+	}
+	if config.Lat == "" || config.Lon == "" {
+		return fmt.Errorf("--lat and --lon must be given together")
+	}
+	lat, err := strconv.ParseFloat(config.Lat, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude must be a number between -90 and 90")
+	}
+	lon, err := strconv.ParseFloat(config.Lon, 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return fmt.Errorf("longitude must be a number between -180 and 180")
+	}
+	if _, err := parseDistance(config.Distance); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Parses distances such as "50", "50mi" or "80km" into kilometers, miles being the default unit
+func parseDistance(s string) (float64, error) {
+	value, unit := splitDistance(s)
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("distance must be a positive number optionally followed by mi or km, e.g. 50mi")
+	}
+	if unit == "km" {
+		return n, nil
+	}
+	return n * kmPerMile, nil
+}
+
+func splitDistance(s string) (string, string) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, unit := range []string{"km", "mi"} {
+		if strings.HasSuffix(s, unit) {
+			return strings.TrimSpace(strings.TrimSuffix(s, unit)), unit
+		}
+	}
+	return s, "mi"
+}
+
+// Great-circle distance in kilometers using the haversine formula
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// Fields added to every repeater when the search has a location
+const (
+	distanceField  = "distance"
+	bearingField   = "bearing"
+	directionField = "direction"
+)
+
+var compassPoints = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// Initial great-circle bearing in degrees clockwise from true north
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLon := toRad(lon2 - lon1)
+	y := math.Sin(dLon) * math.Cos(toRad(lat2))
+	x := math.Cos(toRad(lat1))*math.Sin(toRad(lat2)) - math.Sin(toRad(lat1))*math.Cos(toRad(lat2))*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+func compassPoint(bearing float64) string {
+	return compassPoints[int(math.Round(bearing/22.5))%len(compassPoints)]
+}
+
+// Adds how far each repeater is from the search location, in the given unit, and which way
+func addDistanceFields(records []map[string]interface{}, lat, lon float64, unit string) {
+	for _, record := range records {
+		km, ok := recordDistance(record, lat, lon)
+		if !ok {
+			continue
+		}
+		if unit != "km" {
+			km /= kmPerMile
+		}
+		rLat, _ := recordFloat(record, "Lat")
+		rLon, _ := recordFloat(record, "Long")
+		bearing := initialBearing(lat, lon, rLat, rLon)
+		record[distanceField] = math.Round(km*10) / 10
+		record[bearingField] = math.Round(bearing)
+		record[directionField] = compassPoint(bearing)
+	}
+}
+
+// Distances are given in km or mi as --units says, or else in the unit of --max-distance or --distance
+func distanceUnit(config *Config) string {
+	switch config.Units {
+	case unitsMetric:
+		return "km"
+	case unitsImperial:
+		return "mi"
+	}
+	if config.MaxDistance != "" {
+		_, unit := splitDistance(config.MaxDistance)
+		return unit
+	}
+	_, unit := splitDistance(config.Distance)
+	return unit
+}
+
+// Reads --from as lat,lon coordinates or a grid square. Place names are geocoded to coordinates
+// by resolveLocation before this is needed.
+func parseReference(s string) (float64, float64, bool) {
+	if lat, lon, ok := strings.Cut(s, ","); ok {
+		la, latErr := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		lo, lonErr := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if latErr == nil && lonErr == nil && math.Abs(la) <= 90 && math.Abs(lo) <= 180 {
+			return la, lo, true
+		}
+	}
+	if lat, lon, err := gridToLatLon(strings.TrimSpace(s)); err == nil {
+		return lat, lon, true
+	}
+	return 0, 0, false
+}
+
+// The point distances are measured from: the search center, or --from for searches without one
+func (config *Config) referencePoint() (float64, float64, bool) {
+	if lat, lon, ok := config.location(); ok {
+		return lat, lon, true
+	}
+	if config.From != "" {
+		return parseReference(config.From)
+	}
+	return 0, 0, false
+}
+
+func hasLocation(config *Config) bool {
+	return config.Lat != "" || config.Grid != "" || config.Near != "" || config.NearMe || config.GPS
+}
+
+func validateReference(config *Config) error {
+	if config.From != "" && hasLocation(config) {
+		return fmt.Errorf("--from can't be combined with --lat/--lon, --grid, --near, --near-me or --gps, which already set where distances are measured from")
+	}
+	located := hasLocation(config) || config.From != ""
+	if config.MaxDistance != "" {
+		if _, err := parseDistance(config.MaxDistance); err != nil {
+			return fmt.Errorf("--max-distance: %w", err)
+		}
+		if !located {
+			return fmt.Errorf("--max-distance requires a location (--lat/--lon, --grid, --near, --near-me, --gps or --from)")
+		}
+	}
+	keys, _ := parseSortKeys(config.Sort)
+	for _, key := range keys {
+		if strings.EqualFold(key.field, distanceField) && !located {
+			return fmt.Errorf("--sort distance requires a location (--lat/--lon, --grid, --near, --near-me, --gps or --from)")
+		}
+	}
+	return nil
+}
+
+// Distance and direction for notes and comments, e.g. "12.3 mi NE"
+func distanceNote(record map[string]interface{}, unit string) string {
+	distance := recordString(record, distanceField)
+	if distance == "" {
+		return ""
+	}
+	return strings.TrimSpace(distance + " " + unit + " " + recordString(record, directionField))
+}
+
+func recordDistance(record map[string]interface{}, lat, lon float64) (float64, bool) {
+	rLat, ok := recordFloat(record, "Lat")
+	if !ok {
+		return 0, false
+	}
+	rLon, ok := recordFloat(record, "Long")
+	if !ok {
+		return 0, false
+	}
+	return haversine(lat, lon, rLat, rLon), true
+}
+
+// Maidenhead pairs subdivide the previous pair: 18 fields, 10 squares, 24 subsquares and 10 extended squares
+var gridDivisions = []struct {
+	first byte
+	count int
+}{
+	{'A', 18},
+	{'0', 10},
+	{'A', 24},
+	{'0', 10},
+}
+
+// Converts a Maidenhead locator of 2 to 8 characters (e.g. FN31pr) to the coordinates of its center
+func gridToLatLon(grid string) (float64, float64, error) {
+	g := strings.ToUpper(strings.TrimSpace(grid))
+	if len(g) < 2 || len(g) > 2*len(gridDivisions) || len(g)%2 != 0 {
+		return 0, 0, fmt.Errorf("invalid grid square %q, expected a Maidenhead locator such as FN31pr", grid)
+	}
+	lat, lon := -90.0, -180.0
+	latSize, lonSize := 180.0, 360.0
+	for i := 0; i < len(g); i += 2 {
+		div := gridDivisions[i/2]
+		lonIndex := int(g[i]) - int(div.first)
+		latIndex := int(g[i+1]) - int(div.first)
+		if lonIndex < 0 || lonIndex >= div.count || latIndex < 0 || latIndex >= div.count {
+			return 0, 0, fmt.Errorf("invalid grid square %q, expected a Maidenhead locator such as FN31pr", grid)
+		}
+		latSize /= float64(div.count)
+		lonSize /= float64(div.count)
+		lat += float64(latIndex) * latSize
+		lon += float64(lonIndex) * lonSize
+	}
+	return lat + latSize/2, lon + lonSize/2, nil
+}
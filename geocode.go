@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cartertemm/rbdl/cache"
+)
+
+const (
+	geocodeEndpoint    = "https://nominatim.openstreetmap.org/search"
+	ipLocationEndpoint = "https://ipapi.co/json/"
+)
+
+// Place names rarely move, so geocoding results are cached far longer than API responses
+const geocodeCacheTTL = 30 * 24 * time.Hour
+
+type geocodeResult struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+}
+
+// Looks up a free-text place name with Nominatim, returning its coordinates and full name
+func geocode(query string, config *Config) (float64, float64, string, error) {
+	params := url.Values{}
+	params.Add("q", query)
+	params.Add("format", "json")
+	params.Add("limit", "1")
+	req, err := http.NewRequest("GET", geocodeEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("creating request: %w", err)
+	}
+	// Nominatim's usage policy requires an identifying User-Agent, which needn't be the user's email
+	req.Header.Set("User-Agent", appUserAgent())
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: cache.New(filepath.Join(config.CacheDir, "geocode"), geocodeCacheTTL),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, 0, "", fmt.Errorf("geocoder returned status %d: %s", resp.StatusCode, string(body))
+	}
+	var results []geocodeResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return 0, 0, "", fmt.Errorf("invalid geocoder response: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, 0, "", fmt.Errorf("no location found for %q", query)
+	}
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid latitude in geocoder response: %w", err)
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid longitude in geocoder response: %w", err)
+	}
+	return lat, lon, results[0].DisplayName, nil
+}
+
+type ipLocationResult struct {
+	City      string  `json:"city"`
+	Region    string  `json:"region"`
+	Country   string  `json:"country_name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Error     bool    `json:"error"`
+	Reason    string  `json:"reason"`
+}
+
+// Approximates the user's position from their public IP address. Never cached, since the point is
+// to follow a traveler from one network to the next.
+func locateByIP(config *Config) (float64, float64, string, error) {
+	req, err := http.NewRequest("GET", ipLocationEndpoint, nil)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", appUserAgent())
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, 0, "", fmt.Errorf("IP geolocation returned status %d: %s", resp.StatusCode, string(body))
+	}
+	var result ipLocationResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, 0, "", fmt.Errorf("invalid IP geolocation response: %w", err)
+	}
+	if result.Error {
+		return 0, 0, "", fmt.Errorf("IP geolocation failed: %s", result.Reason)
+	}
+	if result.Latitude == 0 && result.Longitude == 0 {
+		return 0, 0, "", fmt.Errorf("IP geolocation returned no coordinates")
+	}
+	name := result.City
+	if result.Region != "" {
+		name += ", " + result.Region
+	}
+	if result.Country != "" {
+		name += ", " + result.Country
+	}
+	return result.Latitude, result.Longitude, strings.TrimPrefix(name, ", "), nil
+}
+
+// Turns --near, --near-me or --gps into coordinates ahead of the API request
+func resolveLocation(config *Config) error {
+	var lat, lon float64
+	var name string
+	var err error
+	switch {
+	case config.Near != "":
+		lat, lon, name, err = geocode(config.Near, config)
+	case config.NearMe:
+		lat, lon, name, err = locateByIP(config)
+	case config.GPS:
+		lat, lon, err = readGPSPosition(config.GPSDAddr)
+		name = "GPS fix"
+	default:
+		return resolveReference(config)
+	}
+	if err != nil {
+		return err
+	}
+	config.Lat = strconv.FormatFloat(lat, 'f', 5, 64)
+	config.Lon = strconv.FormatFloat(lon, 'f', 5, 64)
+	if !config.Quiet {
+		fmt.Printf("Searching near %s (%s, %s)\n", name, config.Lat, config.Lon)
+	}
+	return nil
+}
+
+// Geocodes a --from place name, leaving coordinates and grid squares as they are
+func resolveReference(config *Config) error {
+	if config.From == "" {
+		return nil
+	}
+	if _, _, ok := parseReference(config.From); ok {
+		return nil
+	}
+	lat, lon, name, err := geocode(config.From, config)
+	if err != nil {
+		return err
+	}
+	config.From = strconv.FormatFloat(lat, 'f', 5, 64) + "," + strconv.FormatFloat(lon, 'f', 5, 64)
+	if !config.Quiet {
+		fmt.Printf("Measuring distances from %s (%s)\n", name, config.From)
+	}
+	return nil
+}
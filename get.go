@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Fetches one repeater by its RepeaterBook state and repeater IDs, printing it or exporting it with --output
+func runGetCommand(args []string) int {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl get state_id rptr_id [options]\n")
+		return 1
+	}
+	id := args[1]
+	fs := flag.NewFlagSet("rbdl get", flag.ExitOnError)
+	config := parseFlags(fs, args[2:])
+	state, country, err := resolveStateIDs(args[0], config.Country)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	// The API can't search by ID, so the whole state is fetched and searched locally
+	config.StateID, config.Country = state, country
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	data, err := fetchRepeaterData(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching data: %v\n", err)
+		return exitCode(err)
+	}
+	data, err = selectRepeater(data, state, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	if config.Output == "" {
+		records, err := parseJSONToRecords(data, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(records) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: repeater %s/%s was excluded by the filters\n", state, id)
+			return exitNoResults
+		}
+		printRecord(os.Stdout, records[0])
+		return 0
+	}
+	if err := saveToFile(config.Output, data, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
+		return exitCode(err)
+	}
+	if !config.Quiet {
+		fmt.Printf("Successfully saved data to: %s\n", config.Output)
+	}
+	return 0
+}
+
+// Narrows an API response down to the repeater with the given IDs
+func selectRepeater(data []byte, state, id string) ([]byte, error) {
+	var response struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("unable to parse API response: %w", err)
+	}
+	key := state + "/" + id
+	for _, record := range response.Results {
+		if recordKey(record) == key {
+			return json.Marshal(map[string]interface{}{
+				"count":   1,
+				"results": []map[string]interface{}{record},
+			})
+		}
+	}
+	return nil, fmt.Errorf("%w for repeater %s", errNoResults, key)
+}
+
+func printRecord(out io.Writer, record map[string]interface{}) {
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		if value := recordString(record, key); value != "" {
+			fmt.Fprintf(w, "%s\t%s\n", key, value)
+		}
+	}
+	w.Flush()
+}
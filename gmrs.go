@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// GMRS repeaters transmit on the eight 462 MHz main channels, numbered 15R-22R by the FCC
+var gmrsRepeaterChannels = map[int]string{
+	462550: "15R",
+	462575: "16R",
+	462600: "17R",
+	462625: "18R",
+	462650: "19R",
+	462675: "20R",
+	462700: "21R",
+	462725: "22R",
+}
+
+// Tone most GMRS repeaters accept in addition to their own, so travelers can access them unannounced
+const gmrsTravelTone = "141.3"
+
+func isGMRS(config *Config) bool {
+	return strings.EqualFold(config.SType, "gmrs")
+}
+
+// Prefers the API's own channel field, falling back to the channel implied by the output frequency
+func gmrsChannel(record map[string]interface{}) string {
+	if channel := recordString(record, "Repeater Channel"); channel != "" {
+		return channel
+	}
+	freq, ok := recordFloat(record, "Frequency")
+	if !ok {
+		return ""
+	}
+	return gmrsRepeaterChannels[int(math.Round(freq*1000))]
+}
+
+func gmrsHasTravelTone(record map[string]interface{}) bool {
+	if travel := recordString(record, "Travel Tone"); travel != "" {
+		return strings.EqualFold(travel, "Yes") || travel == gmrsTravelTone
+	}
+	uplink, _ := recordTones(record)
+	return uplink.String() == gmrsTravelTone
+}
+
+// Channel-prefixed name, e.g. "15R WRAA123", the way GMRS users refer to repeaters
+func gmrsName(record map[string]interface{}) string {
+	name := recordString(record, "Callsign")
+	if channel := gmrsChannel(record); channel != "" {
+		name = strings.TrimSpace(channel + " " + name)
+	}
+	return name
+}
+
+func gmrsComment(record map[string]interface{}) []string {
+	var notes []string
+	if gmrsHasTravelTone(record) {
+		notes = append(notes, fmt.Sprintf("travel tone %s", gmrsTravelTone))
+	}
+	return notes
+}
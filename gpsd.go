@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	defaultGPSDAddr = "localhost:2947"
+	// Long enough for a receiver that has just woken up to report a fix
+	gpsdTimeout = 15 * time.Second
+)
+
+// gpsd time-position-velocity report; mode 2 is a 2D fix and 3 a 3D fix
+type gpsdReport struct {
+	Class string  `json:"class"`
+	Mode  int     `json:"mode"`
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+}
+
+// Asks a gpsd daemon to stream reports and returns the first position with a fix
+func readGPSPosition(addr string) (float64, float64, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return 0, 0, fmt.Errorf("connecting to gpsd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(gpsdTimeout))
+	if _, err := fmt.Fprint(conn, `?WATCH={"enable":true,"json":true};`); err != nil {
+		return 0, 0, fmt.Errorf("sending gpsd watch command: %w", err)
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var report gpsdReport
+		if err := json.Unmarshal(scanner.Bytes(), &report); err != nil {
+			continue
+		}
+		if report.Class == "TPV" && report.Mode >= 2 {
+			return report.Lat, report.Lon, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, fmt.Errorf("no GPS fix from gpsd: %w", err)
+	}
+	return 0, 0, fmt.Errorf("gpsd closed the connection without a GPS fix")
+}
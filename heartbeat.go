@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	heartbeatInterval = 5 * time.Second
+)
+
+// Counts bytes as they are read, so a heartbeat can report progress from another goroutine
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Prints a status line every heartbeatInterval until the returned function is called, so a slow
+// nationwide query doesn't look hung, and a last one with the totals once it ends. Fast queries
+// finish before the first line.
+func startHeartbeat(received *atomic.Int64) func() {
+	start := time.Now()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		printed := false
+		for {
+			select {
+			case <-done:
+				if printed {
+					fmt.Fprintf(os.Stderr, "Finished: %s received in %s\n", formatBytes(received.Load()), time.Since(start).Round(100*time.Millisecond))
+				}
+				return
+			case <-ticker.C:
+				printed = true
+				elapsed := time.Since(start).Round(time.Second)
+				if n := received.Load(); n > 0 {
+					fmt.Fprintf(os.Stderr, "Still downloading: %s received, %s elapsed\n", formatBytes(n), elapsed)
+				} else {
+					fmt.Fprintf(os.Stderr, "Waiting for RepeaterBook to respond, %s elapsed\n", elapsed)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+func isTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// Explains a timed out request, since large unfiltered queries are the usual cause
+func timeoutError(config *Config, received int64, err error) error {
+	return fmt.Errorf("request timed out after %s with %s received: %w. "+
+		"Large searches can take longer than that; narrow the search with --state, --county, --mode or a proximity search, or raise --timeout",
+		config.Timeout, formatBytes(received), err)
+}
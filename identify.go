@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cartertemm/rbdl/cache"
+)
+
+// Radios show a frequency to the 5 kHz step, listings go to the hertz
+const identifyTolerance = 0.003
+
+// How a listed tone compares to the one heard, best first
+const (
+	toneHeard = iota
+	toneUnknown
+	toneDifferent
+)
+
+// Ranks the repeaters on a frequency that was just heard, from saved downloads, a fresh search
+// around a location, or the cached responses of earlier downloads
+func runIdentifyCommand(args []string) int {
+	var inputs []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		inputs = append(inputs, args[0])
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("rbdl identify", flag.ExitOnError)
+	freqFlag := fs.String("freq", "", "Frequency heard, in MHz (e.g., 146.94)")
+	matches := fs.Int("matches", 5, "Most repeaters to list")
+	config := parseFlags(fs, args)
+	heard, err := strconv.ParseFloat(strings.TrimSpace(*freqFlag), 64)
+	if err != nil || heard <= 0 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl identify [saved.json...] --freq MHz [--tone Hz | --dcs code] [--near place] [options]\n")
+		return 1
+	}
+	if *matches <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --matches must be positive\n")
+		return exitInvalid
+	}
+	search := len(inputs) == 0 && (config.StateID != "" || config.Country != "" || config.County != "" || hasLocation(config))
+	check := validateOptions
+	if search {
+		check = validateConfig
+	}
+	if err := check(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	// The tone heard ranks the candidates rather than filtering them, since listings are often wrong
+	var want tone
+	switch {
+	case config.Tone != "":
+		want = parseTone(config.Tone)
+	case config.DCS != "":
+		want = parseTone("D" + dcsCode(config.DCS))
+	}
+	config.Tone, config.DCS = "", ""
+	config.StaticChannels = ""
+	if search {
+		config.StateID, config.Country, _ = resolveStateIDs(config.StateID, config.Country)
+	}
+	if err := resolveLocation(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving location: %v\n", err)
+		return 1
+	}
+	data, err := identifySource(inputs, search, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	candidates := identifyRepeaters(records, heard, want)
+	if len(candidates) == 0 {
+		fmt.Fprintf(os.Stderr, "No repeaters are listed on %.4f MHz in the data searched\n", heard)
+		return exitNoResults
+	}
+	if len(candidates) > *matches {
+		candidates = candidates[:*matches]
+	}
+	printIdentified(os.Stdout, candidates, want, distanceUnit(config))
+	return 0
+}
+
+// Saved downloads when given, a search when one is described, and otherwise every API response in
+// the cache, so a repeater can be identified offline from earlier downloads
+func identifySource(inputs []string, search bool, config *Config) ([]byte, error) {
+	var responses [][]byte
+	switch {
+	case len(inputs) > 0:
+		for _, input := range inputs {
+			data, err := readSavedDownload(input, config)
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, data)
+		}
+	case search:
+		return fetchRepeaterData(config)
+	default:
+		bodies, err := cache.New(config.CacheDir, 0).Bodies()
+		if err != nil {
+			return nil, fmt.Errorf("reading the cache: %w", err)
+		}
+		// Only keep the cached search results, not single-repeater lookups or errors
+		for _, body := range bodies {
+			var response struct {
+				Results []map[string]interface{} `json:"results"`
+			}
+			if json.Unmarshal(body, &response) == nil && len(response.Results) > 0 {
+				responses = append(responses, body)
+			}
+		}
+		if len(responses) == 0 {
+			return nil, fmt.Errorf("nothing cached in %s to search, give saved downloads or a location such as --near", config.CacheDir)
+		}
+	}
+	return mergeResponses(responses)
+}
+
+type identifiedRepeater struct {
+	record map[string]interface{}
+	tone   int
+}
+
+// Repeaters whose output is the frequency heard, those listing the tone heard first, then the
+// closest, then those on the air
+func identifyRepeaters(records []map[string]interface{}, heard float64, want tone) []identifiedRepeater {
+	statuses, _ := parseStatusMap("")
+	var candidates []identifiedRepeater
+	for _, record := range records {
+		output, ok := recordFloat(record, "Frequency")
+		if !ok || math.Abs(output-heard) > identifyTolerance {
+			continue
+		}
+		match := toneUnknown
+		if want.kind != "" {
+			uplink, downlink := recordTones(record)
+			switch {
+			case want.equal(downlink) || want.equal(uplink):
+				match = toneHeard
+			case uplink.kind != accessCarrier || downlink.kind != accessCarrier:
+				match = toneDifferent
+			}
+		}
+		candidates = append(candidates, identifiedRepeater{record: record, tone: match})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.tone != b.tone {
+			return a.tone < b.tone
+		}
+		da, aOK := recordFloat(a.record, distanceField)
+		db, bOK := recordFloat(b.record, distanceField)
+		if aOK != bOK {
+			return aOK
+		}
+		if aOK && da != db {
+			return da < db
+		}
+		return recordStatus(a.record, statuses) == statusOnAir && recordStatus(b.record, statuses) != statusOnAir
+	})
+	return candidates
+}
+
+func printIdentified(w io.Writer, candidates []identifiedRepeater, want tone, unit string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "Callsign\tFrequency\tTone\tLocation\tDistance\tStatus"
+	if want.kind != "" {
+		header += "\tTone Match"
+	}
+	fmt.Fprintln(tw, header)
+	for _, candidate := range candidates {
+		record := candidate.record
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", recordString(record, "Callsign"), recordString(record, "Frequency"),
+			recordString(record, uplinkToneField), cheatSheetLocation(record), distanceNote(record, unit), recordString(record, "Operational Status"))
+		if want.kind != "" {
+			line += "\t" + []string{"yes", "none listed", "no"}[candidate.tone]
+		}
+		fmt.Fprintln(tw, line)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Credentials are stored under this service name, one entry per key
+const keychainService = "rbdl"
+
+var errNoKeychain = errors.New("no OS keychain available")
+
+// The system's credential store, driven through its command-line tool: the macOS Keychain with
+// security, or the Secret Service (GNOME Keyring, KWallet) with secret-tool elsewhere
+type keychain struct {
+	name   string
+	tool   string
+	store  func(key, value string) *exec.Cmd
+	lookup func(key string) *exec.Cmd
+	remove func(key string) *exec.Cmd
+}
+
+func systemKeychain() (*keychain, error) {
+	var k *keychain
+	switch runtime.GOOS {
+	case "darwin":
+		k = &keychain{
+			name: "macOS Keychain",
+			tool: "security",
+			store: func(key, value string) *exec.Cmd {
+				return exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", key, "-w", value)
+			},
+			lookup: func(key string) *exec.Cmd {
+				return exec.Command("security", "find-generic-password", "-s", keychainService, "-a", key, "-w")
+			},
+			remove: func(key string) *exec.Cmd {
+				return exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", key)
+			},
+		}
+	case "windows":
+		// Credential Manager has no tool that reads secrets back, so there's nothing to drive
+		return nil, fmt.Errorf("%w on Windows, use RBDL_EMAIL or the config file instead", errNoKeychain)
+	default:
+		k = &keychain{
+			name: "Secret Service keyring",
+			tool: "secret-tool",
+			store: func(key, value string) *exec.Cmd {
+				cmd := exec.Command("secret-tool", "store", "--label", "rbdl "+key, "service", keychainService, "account", key)
+				// secret-tool reads the secret from stdin, keeping it out of process listings
+				cmd.Stdin = strings.NewReader(value)
+				return cmd
+			},
+			lookup: func(key string) *exec.Cmd {
+				return exec.Command("secret-tool", "lookup", "service", keychainService, "account", key)
+			},
+			remove: func(key string) *exec.Cmd {
+				return exec.Command("secret-tool", "clear", "service", keychainService, "account", key)
+			},
+		}
+	}
+	if _, err := exec.LookPath(k.tool); err != nil {
+		return nil, fmt.Errorf("%w: %s isn't installed", errNoKeychain, k.tool)
+	}
+	return k, nil
+}
+
+func (k *keychain) get(key string) (string, error) {
+	out, err := k.lookup(key).Output()
+	if err != nil {
+		return "", fmt.Errorf("reading %s from the %s: %w", key, k.name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (k *keychain) set(key, value string) error {
+	if out, err := k.store(key, value).CombinedOutput(); err != nil {
+		return fmt.Errorf("saving %s to the %s: %v %s", key, k.name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (k *keychain) delete(key string) error {
+	if out, err := k.remove(key).CombinedOutput(); err != nil {
+		return fmt.Errorf("removing %s from the %s: %v %s", key, k.name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// The email saved with rbdl login, or nothing when there's no keychain or no entry in it
+func keychainEmail() string {
+	k, err := systemKeychain()
+	if err != nil {
+		return ""
+	}
+	email, _ := k.get("email")
+	return email
+}
+
+// Saves the email to the keychain, from the argument or asked for at the prompt
+func runLoginCommand(args []string) int {
+	if len(args) > 1 || len(args) == 1 && strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl login [email]\n")
+		return 1
+	}
+	k, err := systemKeychain()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var email string
+	if len(args) == 1 {
+		email = args[0]
+	} else {
+		fmt.Fprintf(os.Stderr, "Email for the RepeaterBook User-Agent: ")
+		email = readLine(os.Stdin)
+	}
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		fmt.Fprintf(os.Stderr, "Error: %q isn't an email address\n", email)
+		return exitInvalid
+	}
+	if err := k.set("email", email); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Saved %s to the %s\n", email, k.name)
+	return 0
+}
+
+func runLogoutCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl logout\n")
+		return 1
+	}
+	k, err := systemKeychain()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := k.delete("email"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Removed the email from the %s\n", k.name)
+	return 0
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+func validateLimits(config *Config) error {
+	if config.Limit < 0 || config.Sample < 0 {
+		return fmt.Errorf("--limit and --sample must be positive")
+	}
+	if config.Limit > 0 && config.Sample > 0 {
+		return fmt.Errorf("--limit can't be combined with --sample")
+	}
+	if config.Seed != 0 && config.Sample == 0 {
+		return fmt.Errorf("--seed only applies to --sample")
+	}
+	return nil
+}
+
+// Keeps the first --limit records, or --sample records picked at random in their existing order.
+// A --seed makes the sample repeatable. Favorites are always kept and count towards the limit.
+func limitRecords(records []map[string]interface{}, config *Config) []map[string]interface{} {
+	if config.Limit == 0 && config.Sample == 0 {
+		return records
+	}
+	var favorites, rest []map[string]interface{}
+	for _, record := range records {
+		if isFavorite(record) {
+			favorites = append(favorites, record)
+		} else {
+			rest = append(rest, record)
+		}
+	}
+	if config.Limit > 0 {
+		if room := max(config.Limit-len(favorites), 0); len(rest) > room {
+			rest = rest[:room]
+		}
+		return append(favorites, rest...)
+	}
+	room := max(config.Sample-len(favorites), 0)
+	if len(rest) <= room {
+		return append(favorites, rest...)
+	}
+	var r *rand.Rand
+	if config.Seed != 0 {
+		r = rand.New(rand.NewSource(config.Seed))
+	} else {
+		r = rand.New(rand.NewSource(rand.Int63()))
+	}
+	picked := r.Perm(len(rest))[:room]
+	sort.Ints(picked)
+	for _, i := range picked {
+		favorites = append(favorites, rest[i])
+	}
+	return favorites
+}
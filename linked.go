@@ -0,0 +1,102 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Derived field naming the linked system a repeater appears to belong to, with --linked-systems
+const linkedSystemField = "linked_system"
+
+// Notes wording that says a repeater is tied to others
+var linkNotes = regexp.MustCompile(`(?i)\b(link|linked|links|linking|network|intertie|system)\b`)
+
+func wantsLinkedSystems(config *Config) bool {
+	return config.LinkedSystems || config.Zones == "linked" || config.ScanLists == "linked"
+}
+
+// Guesses which repeaters form a linked system: machines sharing a callsign, machines of the same
+// sponsor whose notes both talk of links or a network, and machines whose notes talk of links and
+// name another one's callsign. Only groups of two or more are named, after their shared sponsor or
+// else their callsign.
+func assignLinkedSystems(records []map[string]interface{}, config *Config) {
+	if !wantsLinkedSystems(config) {
+		return
+	}
+	parent := make([]int, len(records))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		parent[find(i)] = find(j)
+	}
+	byCall := make(map[string]int)
+	bySponsor := make(map[string]int)
+	for i, record := range records {
+		if call := normalizeCallsign(recordString(record, "Callsign")); call != "" {
+			if j, ok := byCall[call]; ok {
+				union(i, j)
+			} else {
+				byCall[call] = i
+			}
+		}
+		sponsor := strings.ToLower(strings.TrimSpace(recordString(record, sponsorField)))
+		if sponsor == "" || !linkNotes.MatchString(recordString(record, "Notes")) {
+			continue
+		}
+		if j, ok := bySponsor[sponsor]; ok {
+			union(i, j)
+		} else {
+			bySponsor[sponsor] = i
+		}
+	}
+	for i, record := range records {
+		notes := recordString(record, "Notes")
+		if !linkNotes.MatchString(notes) {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(notes, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '/'
+		}) {
+			if j, ok := byCall[normalizeCallsign(word)]; ok {
+				union(i, j)
+			}
+		}
+	}
+	members := make(map[int][]int)
+	for i := range records {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		name := linkedSystemName(records, group)
+		for _, i := range group {
+			records[i][linkedSystemField] = name
+		}
+	}
+}
+
+func linkedSystemName(records []map[string]interface{}, group []int) string {
+	sponsor := recordString(records[group[0]], sponsorField)
+	for _, i := range group[1:] {
+		if !strings.EqualFold(recordString(records[i], sponsorField), sponsor) {
+			sponsor = ""
+			break
+		}
+	}
+	if strings.TrimSpace(sponsor) != "" {
+		return strings.TrimSpace(sponsor)
+	}
+	return normalizeCallsign(recordString(records[group[0]], "Callsign"))
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Listings not updated for this long are reported as stale unless --stale-before says otherwise
+const defaultStaleYears = 2
+
+// Repeaters sharing an output closer than this are reported as possible co-channel conflicts
+const defaultConflictDistance = "50mi"
+
+// Repeaters sharing an output frequency in one county, or one city where the county isn't listed
+type duplicateFrequency struct {
+	Frequency string                   `json:"frequency"`
+	Area      string                   `json:"area"`
+	Repeaters []map[string]interface{} `json:"repeaters"`
+}
+
+// Two repeaters on the same output frequency within --conflict-distance of each other
+type coChannelConflict struct {
+	Frequency string                    `json:"frequency"`
+	Distance  float64                   `json:"distance"`
+	Unit      string                    `json:"unit"`
+	Repeaters [2]map[string]interface{} `json:"repeaters"`
+}
+
+type lintReport struct {
+	MissingCoordinates []map[string]interface{} `json:"missing_coordinates"`
+	MissingTones       []map[string]interface{} `json:"missing_tones"`
+	ZeroOffsets        []map[string]interface{} `json:"zero_offsets"`
+	Stale              []map[string]interface{} `json:"stale"`
+	Duplicates         []duplicateFrequency     `json:"duplicate_frequencies"`
+	Conflicts          []coChannelConflict      `json:"co_channel_conflicts"`
+}
+
+func (report lintReport) problems() int {
+	return len(report.MissingCoordinates) + len(report.MissingTones) + len(report.ZeroOffsets) + len(report.Stale) + len(report.Duplicates) + len(report.Conflicts)
+}
+
+// Reports listings that need attention from a state's RepeaterBook admins, from a saved download or
+// a fresh search
+func runLintCommand(args []string) int {
+	saved := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		saved = args[0]
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("rbdl lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	staleBefore := fs.String("stale-before", "", fmt.Sprintf("Report listings last updated before this date (default %d years ago)", defaultStaleYears))
+	conflictDistance := fs.String("conflict-distance", defaultConflictDistance, "Report repeaters sharing an output frequency closer than this (e.g., 50mi, 80km)")
+	config := parseFlags(fs, args)
+	// Zero offsets are reported as listed rather than filled in from the band plan
+	config.NoInferOffset = true
+	// Only the listings are checked, not the user's own channels
+	config.StaticChannels = ""
+	check := validateConfig
+	if saved != "" {
+		check = validateOptions
+	}
+	if err := check(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	cutoff := time.Now().AddDate(-defaultStaleYears, 0, 0)
+	if *staleBefore != "" {
+		var err error
+		if cutoff, err = time.Parse("2006-01-02", strings.TrimSpace(*staleBefore)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --stale-before must be a date such as 2024-01-01\n")
+			return exitInvalid
+		}
+	}
+	conflictKm, err := parseDistance(*conflictDistance)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --conflict-distance: %v\n", err)
+		return exitInvalid
+	}
+	var data []byte
+	if saved != "" {
+		data, err = readSavedDownload(saved, config)
+	} else {
+		config.StateID, config.Country, _ = resolveStateIDs(config.StateID, config.Country)
+		if err = resolveLocation(config); err == nil {
+			data, err = fetchRepeaterData(config)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	report := lintRecords(records, cutoff)
+	_, unit := splitDistance(*conflictDistance)
+	report.Conflicts = coChannelConflicts(records, conflictKm, unit)
+	if *asJSON {
+		encoded, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(encoded))
+		return 0
+	}
+	printLint(os.Stdout, report, cutoff, len(records))
+	return 0
+}
+
+func lintRecords(records []map[string]interface{}, cutoff time.Time) lintReport {
+	report := lintReport{
+		MissingCoordinates: []map[string]interface{}{},
+		MissingTones:       []map[string]interface{}{},
+		ZeroOffsets:        []map[string]interface{}{},
+		Stale:              []map[string]interface{}{},
+		Duplicates:         []duplicateFrequency{},
+	}
+	type areaFrequency struct{ area, freq string }
+	shared := make(map[areaFrequency][]map[string]interface{})
+	var order []areaFrequency
+	for _, record := range records {
+		lat, latOK := recordFloat(record, "Lat")
+		lon, lonOK := recordFloat(record, "Long")
+		if !latOK || !lonOK || lat == 0 && lon == 0 {
+			report.MissingCoordinates = append(report.MissingCoordinates, record)
+		}
+		// Digital-only repeaters have no tone to list
+		if recordString(record, "FM Analog") == "Yes" && recordString(record, "PL") == "" {
+			report.MissingTones = append(report.MissingTones, record)
+		}
+		output, ok := recordFloat(record, "Frequency")
+		if !ok || output <= 0 {
+			continue
+		}
+		if input, ok := recordFloat(record, "Input Freq"); !ok || input == 0 || math.Abs(input-output) < 0.0005 {
+			report.ZeroOffsets = append(report.ZeroOffsets, record)
+		}
+		if !updatedSince(record, cutoff) {
+			report.Stale = append(report.Stale, record)
+		}
+		if area := lintArea(record); area != "" {
+			key := areaFrequency{area, fmt.Sprintf("%.4f", output)}
+			if shared[key] == nil {
+				order = append(order, key)
+			}
+			shared[key] = append(shared[key], record)
+		}
+	}
+	for _, key := range order {
+		if len(shared[key]) > 1 {
+			report.Duplicates = append(report.Duplicates, duplicateFrequency{Frequency: key.freq, Area: key.area, Repeaters: shared[key]})
+		}
+	}
+	sort.SliceStable(report.Duplicates, func(i, j int) bool {
+		return report.Duplicates[i].Frequency < report.Duplicates[j].Frequency
+	})
+	return report
+}
+
+// Pairs up repeaters on the same output frequency within km of each other, closest first. Repeaters
+// without coordinates can't be placed and are left out.
+func coChannelConflicts(records []map[string]interface{}, km float64, unit string) []coChannelConflict {
+	conflicts := []coChannelConflict{}
+	byFrequency := make(map[string][]map[string]interface{})
+	for _, record := range records {
+		output, ok := recordFloat(record, "Frequency")
+		lat, latOK := recordFloat(record, "Lat")
+		lon, lonOK := recordFloat(record, "Long")
+		if !ok || output <= 0 || !latOK || !lonOK || lat == 0 && lon == 0 {
+			continue
+		}
+		freq := fmt.Sprintf("%.4f", output)
+		byFrequency[freq] = append(byFrequency[freq], record)
+	}
+	for freq, shared := range byFrequency {
+		for i, a := range shared {
+			lat, _ := recordFloat(a, "Lat")
+			lon, _ := recordFloat(a, "Long")
+			for _, b := range shared[i+1:] {
+				apart, _ := recordDistance(b, lat, lon)
+				if apart > km {
+					continue
+				}
+				if unit == "mi" {
+					apart /= kmPerMile
+				}
+				conflicts = append(conflicts, coChannelConflict{Frequency: freq, Distance: math.Round(apart*10) / 10, Unit: unit, Repeaters: [2]map[string]interface{}{a, b}})
+			}
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Distance != conflicts[j].Distance {
+			return conflicts[i].Distance < conflicts[j].Distance
+		}
+		return conflicts[i].Frequency < conflicts[j].Frequency
+	})
+	return conflicts
+}
+
+// The county and state, e.g. Gallatin, Montana, falling back to the nearest city
+func lintArea(record map[string]interface{}) string {
+	state := recordString(record, "State")
+	if county := recordString(record, "County"); county != "" {
+		return strings.TrimSuffix(county+", "+state, ", ")
+	}
+	return cheatSheetLocation(record)
+}
+
+func printLint(w io.Writer, report lintReport, cutoff time.Time, total int) {
+	sections := []struct {
+		title   string
+		records []map[string]interface{}
+		updated bool
+	}{
+		{"Missing coordinates", report.MissingCoordinates, false},
+		{"Missing tones", report.MissingTones, false},
+		{"Zero offsets", report.ZeroOffsets, false},
+		{"Not updated since " + cutoff.Format("2006-01-02"), report.Stale, true},
+	}
+	for _, section := range sections {
+		if len(section.records) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", section.title, len(section.records))
+		for _, record := range section.records {
+			if updated := recordString(record, lastUpdateField); section.updated && updated != "" {
+				fmt.Fprintf(w, "  %s, last updated %s\n", diffLabel(record), updated)
+				continue
+			}
+			fmt.Fprintf(w, "  %s\n", diffLabel(record))
+		}
+	}
+	if len(report.Duplicates) > 0 {
+		fmt.Fprintf(w, "Duplicate frequencies (%d):\n", len(report.Duplicates))
+		for _, duplicate := range report.Duplicates {
+			calls := make([]string, len(duplicate.Repeaters))
+			for i, record := range duplicate.Repeaters {
+				calls[i] = recordString(record, "Callsign")
+			}
+			fmt.Fprintf(w, "  %s in %s: %s\n", duplicate.Frequency, duplicate.Area, strings.Join(calls, ", "))
+		}
+	}
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(w, "Possible co-channel conflicts (%d):\n", len(report.Conflicts))
+		for _, conflict := range report.Conflicts {
+			a, b := conflict.Repeaters[0], conflict.Repeaters[1]
+			fmt.Fprintf(w, "  %s: %s and %s, %.1f %s apart", conflict.Frequency, conflictLabel(a), conflictLabel(b), conflict.Distance, conflict.Unit)
+			if toneA, toneB := recordString(a, "PL"), recordString(b, "PL"); toneA != "" || toneB != "" {
+				fmt.Fprintf(w, ", tones %s and %s", diffValue(toneA), diffValue(toneB))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "%d problems in %d repeaters\n", report.problems(), total)
+}
+
+// A callsign and where the repeater is, e.g. W7YB (Bozeman, Montana)
+func conflictLabel(record map[string]interface{}) string {
+	if location := cheatSheetLocation(record); location != "" {
+		return recordString(record, "Callsign") + " (" + location + ")"
+	}
+	return recordString(record, "Callsign")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// One line of an --exclude-file or --include-file: a callsign, an output frequency, or both to
+// pick out one machine of a callsign that has several
+type listEntry struct {
+	call string
+	freq float64
+}
+
+type repeaterList []listEntry
+
+// Reads a list of repeaters, one per line as W7YB, 146.88 or W7YB 146.88, with # comments
+func loadRepeaterList(path, flag string) (repeaterList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", flag, err)
+	}
+	defer file.Close()
+	var list repeaterList
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		var entry listEntry
+		for _, field := range fields {
+			if freq, err := strconv.ParseFloat(field, 64); err == nil && entry.freq == 0 {
+				entry.freq = freq
+			} else if entry.call == "" && strings.ContainsAny(field, "0123456789") {
+				entry.call = normalizeCallsign(field)
+			} else {
+				return nil, fmt.Errorf("%s line %d: expected a callsign, a frequency or both, got %q", flag, line, strings.TrimSpace(text))
+			}
+		}
+		list = append(list, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	return list, nil
+}
+
+func (list repeaterList) matches(record map[string]interface{}) bool {
+	call := normalizeCallsign(recordString(record, "Callsign"))
+	freq, hasFreq := recordFloat(record, "Frequency")
+	for _, entry := range list {
+		if entry.call != "" && entry.call != call {
+			continue
+		}
+		if entry.freq != 0 && (!hasFreq || math.Abs(entry.freq-freq) > 0.0005) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func validateListFiles(config *Config) error {
+	for _, file := range []struct{ flag, path string }{
+		{"--exclude-file", config.ExcludeFile},
+		{"--include-file", config.IncludeFile},
+		{"--favorites", config.Favorites},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := loadRepeaterList(file.path, file.flag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Puts back the --include-file repeaters the local filters dropped
+func restoreIncluded(records, included []map[string]interface{}) []map[string]interface{} {
+	present := make(map[string]bool)
+	for _, record := range records {
+		for _, key := range recordKeys(record) {
+			present[key] = true
+		}
+	}
+	for _, record := range included {
+		found := false
+		for _, key := range recordKeys(record) {
+			found = found || present[key]
+		}
+		if !found {
+			records = append(records, record)
+		}
+	}
+	return records
+}
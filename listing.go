@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Country names as RepeaterBook spells them, mostly served by the rest of world endpoint
+var countryNames = []string{
+	"Argentina", "Australia", "Austria", "Bahamas", "Belgium", "Bolivia", "Bosnia and Herzegovina",
+	"Brazil", "Bulgaria", "Canada", "Chile", "China", "Colombia", "Costa Rica", "Croatia", "Cuba", "Cyprus",
+	"Czech Republic", "Denmark", "Dominican Republic", "Ecuador", "El Salvador", "Estonia", "Finland",
+	"France", "Germany", "Greece", "Guatemala", "Honduras", "Hungary", "Iceland", "India", "Indonesia",
+	"Ireland", "Israel", "Italy", "Jamaica", "Japan", "Latvia", "Lithuania", "Luxembourg", "Malaysia",
+	"Malta", "Mexico", "Netherlands", "New Zealand", "Nicaragua", "Norway", "Panama", "Paraguay", "Peru",
+	"Philippines", "Poland", "Portugal", "Romania", "Serbia", "Singapore", "Slovakia", "Slovenia",
+	"South Africa", "South Korea", "Spain", "Sweden", "Switzerland", "Taiwan", "Thailand",
+	"Trinidad and Tobago", "Turkey", "Ukraine", "United Kingdom", "United States", "Uruguay", "Venezuela",
+}
+
+func runStatesCommand(args []string) int {
+	if len(args) > 1 || (len(args) == 1 && strings.HasPrefix(args[0], "-")) {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl states [country]\n")
+		return 1
+	}
+	country := ""
+	if len(args) == 1 {
+		country = args[0]
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Code\tAbbrev\tName\tCountry\n")
+	found := false
+	for _, state := range stateCodes {
+		if country != "" && !strings.EqualFold(country, state.Country) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", state.Code, state.Abbrev, state.Name, state.Country)
+		found = true
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "No state codes known for %s, search it with --country alone\n", country)
+		return 1
+	}
+	w.Flush()
+	return 0
+}
+
+func runCountriesCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl countries\n")
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Country\tEndpoint\n")
+	for _, country := range countryNames {
+		endpoint := "row"
+		if northAmericaCountries[strings.ToLower(country)] {
+			endpoint = "na"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", country, endpoint)
+	}
+	w.Flush()
+	return 0
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -11,91 +10,488 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/cartertemm/rbdl/cache"
 )
 
 const (
 	apiEndpoint       = "https://www.repeaterbook.com/api/export.php"
+	rowEndpoint       = "https://www.repeaterbook.com/api/exportROW.php"
 	userAgentTemplate = "RepeaterbookDL CLI (beta), %s"
 )
 
+// Services other than RepeaterBook are sent this instead, so the email stays with the API it's for
+func appUserAgent() string {
+	return fmt.Sprintf("rbdl/%s (+https://github.com/cartertemm/rbdl)", version)
+}
+
+// Set at build time with -ldflags "-X main.version=... -X main.commit=...". Without them the commit
+// is read from the build's VCS stamp where available.
+var (
+	version = "dev"
+	commit  = ""
+)
+
+var outputFormats = []string{"json", "csv", "pdf", "msgpack", "chirp", "garmin", "template"}
+
+// File extensions recognized when auto-detecting the output format
+var formatExtensions = map[string]string{
+	".json":    "json",
+	".csv":     "csv",
+	".pdf":     "pdf",
+	".msgpack": "msgpack",
+	".mpk":     "msgpack",
+}
+
 type Config struct {
-	Email     string
-	Output    string
-	Format    string
-	OnAir     bool
-	Callsign  string
-	City      string
-	Country   string
-	Frequency string
-	Mode      string
-	Landmark  string
-	StateID   string
-	Region    string
-	SType     string
+	Email            string
+	Output           string
+	OutputDir        string
+	FilenameTemplate string
+	Force            bool
+	SplitBy          string
+	Append           bool
+	Keep             int
+	Format           string
+	OnAir            bool
+	Status           string
+	Use              string
+	EmergencyPower   bool
+	UpdatedSince     string
+	Tone             string
+	DCS              string
+	StatusMap        string
+	ExcludeFile      string
+	IncludeFile      string
+	StaticChannels   string
+	Favorites        string
+	ClubRoster       string
+	OnlyClub         bool
+	Sponsor          string
+	Callsign         string
+	City             string
+	CallsignPrefix   string
+	CityContains     string
+	LandmarkContains string
+	Country          string
+	Frequency        string
+	Band             string
+	DMRNetwork       string
+	ColorCode        string
+	Talkgroup        string
+	RadioID          bool
+	NXDNRAN          string
+	P25NAC           string
+	HasEchoLink      bool
+	HasIRLP          bool
+	HasAllStar       bool
+	FreqMin          string
+	FreqMax          string
+	Mode             string
+	DStar            bool
+	Fusion           bool
+	NXDN             bool
+	P25              bool
+	AnalogOnly       bool
+	Landmark         string
+	StateID          string
+	County           string
+	Region           string
+	SType            string
+	Lat              string
+	Lon              string
+	Distance         string
+	MaxDistance      string
+	Units            string
+	From             string
+	Grid             string
+	Near             string
+	NearMe           bool
+	GPS              bool
+	GPSDAddr         string
+	Endpoint         string
+	ArchiveLayout    string
+	CSVDelimiter     string
+	CSVQuote         string
+	CSVBOM           bool
+	CSVCRLF          bool
+	HeaderComment    bool
+	Encoding         string
+	Coords           string
+	SwapRxTx         bool
+	NoInferOffset    bool
+	BandPlanReport   string
+	ExpandTalkgroups bool
+	Zones            string
+	ZoneSize         string
+	ZoneRing         string
+	ScanLists        string
+	LinkedSystems    bool
+	AddTalkaround    bool
+	IncludeSimplex   bool
+	IncludeWeather   bool
+	DualWatchRules   string
+	AutoPower        bool
+	PowerThresholds  string
+	Throttle         time.Duration
+	Columns          string
+	PruneEmpty       bool
+	ValueMap         string
+	Template         string
+	ChannelName      string
+	NameLength       string
+	StartChannel     int
+	Bank             int
+	BankSize         int
+	BankBands        string
+	Match            string
+	Where            string
+	Sort             string
+	Limit            int
+	Sample           int
+	MaxChannels      int
+	Priority         string
+	Seed             int64
+	ShowVersion      bool
+	DryRun           bool
+	Preview          int
+	Quiet            bool
+	Verbose          bool
+	Debug            bool
+	RedactEmail      bool
+	Timeout          time.Duration
+	CacheTTL         time.Duration
+	CacheDir         string
+	Transcript       string
+	Preset           string
+	Profile          string
+	ConfigFile       string
+	// Where option values came from besides flags and the environment, for reporting
+	file              *configFile
+	fileOptions       map[string]bool
+	profileOptions    map[string]bool
+	presetOptions     map[string]bool
+	emailFromKeychain bool
+	// Repeaters already in the output file, which --append merges the results into
+	appendTo []map[string]interface{}
+	// Set when the records were parsed before, as a browse export's are, so the steps that changed
+	// and filtered them aren't repeated
+	processed bool
 }
 
 func main() {
-	config := parseFlags()
-	if err := validateConfig(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	if needsSetup(os.Args[1:]) {
+		os.Exit(runSetupCommand(nil))
+	}
+	os.Exit(runFetchCommand(os.Args[1:]))
+}
+
+// Fetches, filters and saves one download, recording it in transcript when non-nil
+func runDownload(config *Config, transcript *Transcript) int {
+	// Already checked by validateConfig
+	config.StateID, config.Country, _ = resolveStateIDs(config.StateID, config.Country)
+	if config.DryRun {
+		return printDryRun(os.Stdout, config)
 	}
-	data, err := fetchRepeaterData(config)
+	if err := resolveLocation(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving location: %v\n", err)
+		return 1
+	}
+	responses, err := fetchAll(expandQueries(config), config.Throttle, transcript)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error fetching data: %v\n", err)
-		os.Exit(1)
+		return exitCode(err)
 	}
-	outputFile := config.Output
-	if outputFile == "" {
-		outputFile = generateFilename(config)
+	data, err := mergeResponses(responses)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging results: %v\n", err)
+		return 1
 	}
-	if err := saveToFile(outputFile, data, config); err != nil {
+	// A preview replaces the download unless --output asks for the file as well
+	if config.Preview > 0 {
+		records, err := parseJSONToRecords(data, config)
+		if err == nil && len(records) == 0 {
+			err = fmt.Errorf("%w to preview", errNoResults)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitCode(err)
+		}
+		printPreview(os.Stdout, records, config.Preview)
+		if config.Output == "" {
+			return 0
+		}
+	}
+	groups, err := splitResponse(data, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	paths := groupPaths(groups, config)
+	for i, outputFile := range paths {
+		if err := makeOutputDir(outputFile, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if config.ArchiveLayout != "" {
+			archived, err := archivePath(outputFile, config.ArchiveLayout, time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating archive directory: %v\n", err)
+				return 1
+			}
+			paths[i] = archived
+		}
+	}
+	saved, err := saveGroups(groups, paths, config)
+	for _, outputFile := range saved {
+		transcript.addOutput(outputFile, config.Format)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
-		os.Exit(1)
+		return exitCode(err)
+	}
+	if config.Keep > 0 {
+		removed, err := pruneCopies(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(removed) > 0 && !config.Quiet {
+			fmt.Printf("Removed %d older copies, keeping the newest %d\n", len(removed), config.Keep)
+		}
+	}
+	if config.Transcript != "" {
+		if err := transcript.save(config.Transcript, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving transcript: %v\n", err)
+			return 1
+		}
+		if !config.Quiet {
+			fmt.Printf("Transcript saved to: %s\n", config.Transcript)
+		}
 	}
-	fmt.Printf("Successfully saved data to: %s\n", outputFile)
+	return 0
 }
 
-func parseFlags() *Config {
+func parseFlags(fs *flag.FlagSet, args []string) *Config {
 	config := &Config{}
-	flag.StringVar(&config.Email, "email", os.Getenv("RBDL_EMAIL"), "Email address (required, or set RBDL_EMAIL)")
-	flag.StringVar(&config.Output, "output", "", "Output file path (auto-generated if not specified)")
-	flag.StringVar(&config.Format, "format", "", "Output format: json or csv (auto-detected from output filename if not specified)")
-	flag.BoolVar(&config.OnAir, "on-air", false, "Only include on-air repeaters")
-	flag.StringVar(&config.Callsign, "callsign", "", "Repeater callsign (supports % wildcard)")
-	flag.StringVar(&config.City, "city", "", "Repeater city (supports % wildcard)")
-	flag.StringVar(&config.Country, "country", "", "Repeater country (supports % wildcard)")
-	flag.StringVar(&config.Frequency, "frequency", "", "Repeater frequency")
-	flag.StringVar(&config.Mode, "mode", "", "Operating mode (analog, DMR, NXDN, P25, tetra)")
-	flag.StringVar(&config.Landmark, "landmark", "", "Landmark (supports % wildcard)")
-	flag.StringVar(&config.StateID, "state", "", "State/Province FIPS code")
-	flag.StringVar(&config.Region, "region", "", "Region (for international repeaters)")
-	flag.StringVar(&config.SType, "stype", "", "Service type (e.g., GMRS)")
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: rbdl [options]\n\n")
+	predefined := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		predefined[f.Name] = true
+	})
+	fs.StringVar(&config.Email, "email", "", "Email address (required, or set RBDL_EMAIL)")
+	fs.StringVar(&config.ConfigFile, "config", defaultConfigPath(), "TOML file of default options")
+	fs.StringVar(&config.Preset, "preset", "", "Start from a named bundle of options, see rbdl presets")
+	fs.StringVar(&config.Profile, "profile", "", "Use a [profiles.NAME] table of options from the config file")
+	fs.StringVar(&config.Output, "output", "", "Output file path (auto-generated if not specified)")
+	fs.StringVar(&config.Output, "o", "", "Same as --output")
+	fs.StringVar(&config.OutputDir, "output-dir", "", "Directory for output files, created if needed")
+	fs.StringVar(&config.FilenameTemplate, "filename", "", "Name for generated output files, e.g. {state}_{mode}_{date}.{ext} (default "+defaultFilenameTemplate+")")
+	fs.StringVar(&config.SplitBy, "split-by", "", "Write a file per band, mode, state or county, named after the group (e.g., montana_2m.csv)")
+	fs.BoolVar(&config.Append, "append", false, "Merge results into an existing json or csv output, replacing repeaters already in it")
+	fs.IntVar(&config.Keep, "keep", 0, "Timestamp outputs and keep only the newest N copies, deleting older ones")
+	fs.BoolVar(&config.Force, "force", false, "Overwrite existing output files without asking")
+	fs.StringVar(&config.Format, "format", "", "Output format: json, csv, pdf, msgpack, chirp, garmin or template (auto-detected from output filename if not specified)")
+	fs.BoolVar(&config.OnAir, "on-air", false, "Only include on-air repeaters, short for --status on-air")
+	fs.Var(newListFlag(&config.Status), "status", "Only include repeaters with these operational statuses: on-air, off-air, testing or unknown")
+	fs.StringVar(&config.DMRNetwork, "dmr-network", "", "Only include DMR repeaters on this network (e.g., BrandMeister)")
+	fs.StringVar(&config.ColorCode, "color-code", "", "Only include DMR repeaters using this color code")
+	fs.BoolVar(&config.RadioID, "radioid", false, "Check DMR repeaters against RadioID.net, filling in missing DMR IDs and networks")
+	fs.StringVar(&config.Talkgroup, "talkgroup", "", "Only include DMR repeaters listing this talkgroup (e.g., 3100)")
+	fs.StringVar(&config.NXDNRAN, "nxdn-ran", "", "Only include NXDN repeaters using this RAN (0-63)")
+	fs.StringVar(&config.P25NAC, "p25-nac", "", "Only include P25 repeaters using this NAC, in hex (e.g., 293)")
+	fs.BoolVar(&config.HasEchoLink, "has-echolink", false, "Only include repeaters with an EchoLink node")
+	fs.BoolVar(&config.HasIRLP, "has-irlp", false, "Only include repeaters with an IRLP node")
+	fs.BoolVar(&config.HasAllStar, "has-allstar", false, "Only include repeaters with an AllStar node")
+	fs.Var(newListFlag(&config.Use), "use", "Only include repeaters with these uses: open, closed or private")
+	fs.StringVar(&config.Tone, "tone", "", "Only include repeaters using this CTCSS tone in Hz (e.g., 100.0)")
+	fs.StringVar(&config.DCS, "dcs", "", "Only include repeaters using this DCS code (e.g., 023)")
+	fs.StringVar(&config.UpdatedSince, "updated-since", "", "Only include repeaters whose listing was updated on or after this date (e.g., 2024-01-01)")
+	fs.BoolVar(&config.EmergencyPower, "emergency-power", false, "Only include repeaters with backup power")
+	fs.StringVar(&config.StatusMap, "status-map", "", "Extra operational status spellings, e.g. \"Temporarily Down=off-air,Beta=testing\"")
+	fs.StringVar(&config.ExcludeFile, "exclude-file", "", "File of callsigns or frequencies, one per line, to drop from every export")
+	fs.StringVar(&config.IncludeFile, "include-file", "", "File of callsigns or frequencies, one per line, to keep whatever the other filters say")
+	fs.StringVar(&config.StaticChannels, "static-channels", "", "CSV of channels, with field names as headers, added to every export and merged with downloaded repeaters they duplicate")
+	fs.StringVar(&config.Favorites, "favorites", "", "File of callsigns or frequencies, one per line, listed first and never cut by --limit or --sample")
+	fs.StringVar(&config.ClubRoster, "club-roster", "", "CSV of club member callsigns, tagging repeaters whose trustee is a member")
+	fs.BoolVar(&config.OnlyClub, "only-club", false, "Only include repeaters whose trustee is on the --club-roster")
+	fs.StringVar(&config.Sponsor, "sponsor", "", "Only include repeaters whose sponsoring club contains this text (supports % wildcard)")
+	fs.StringVar(&config.Sponsor, "affiliate", "", "Same as --sponsor")
+	fs.StringVar(&config.Callsign, "callsign", "", "Repeater callsign (supports % wildcard)")
+	fs.StringVar(&config.City, "city", "", "Repeater city (supports % wildcard)")
+	fs.StringVar(&config.CallsignPrefix, "callsign-prefix", "", "Repeater callsigns starting with this text, without typing a % wildcard (e.g., W7)")
+	fs.StringVar(&config.CityContains, "city-contains", "", "Repeater cities containing this text, without typing % wildcards")
+	fs.StringVar(&config.LandmarkContains, "landmark-contains", "", "Landmarks containing this text, without typing % wildcards")
+	fs.Var(newListFlag(&config.Country), "country", "Repeater country (supports % wildcard), repeat or comma-separate for several")
+	fs.StringVar(&config.Frequency, "frequency", "", "Repeater frequency")
+	fs.StringVar(&config.FreqMin, "freq-min", "", "Only include repeaters at or above this frequency in MHz")
+	fs.StringVar(&config.FreqMax, "freq-max", "", "Only include repeaters at or below this frequency in MHz")
+	fs.Var(newListFlag(&config.Band), "band", "Only include repeaters on these bands, repeat or comma-separate for several (e.g., 2m,70cm)")
+	fs.StringVar(&config.Mode, "mode", "", "Operating mode (analog, DMR, NXDN, P25, tetra)")
+	fs.BoolVar(&config.DStar, "dstar", false, "Only include D-Star repeaters")
+	fs.BoolVar(&config.Fusion, "fusion", false, "Only include System Fusion repeaters")
+	fs.BoolVar(&config.NXDN, "nxdn", false, "Only include NXDN repeaters")
+	fs.BoolVar(&config.P25, "p25", false, "Only include P25 repeaters")
+	fs.BoolVar(&config.AnalogOnly, "analog-only", false, "Only include analog FM repeaters without any digital mode")
+	fs.StringVar(&config.Landmark, "landmark", "", "Landmark (supports % wildcard)")
+	fs.Var(newListFlag(&config.StateID), "state", "State/Province name, postal abbreviation or FIPS code, repeat or comma-separate for several")
+	fs.StringVar(&config.County, "county", "", "County (supports % wildcard), best combined with --state since county names repeat across states")
+	fs.StringVar(&config.Region, "region", "", "Region (for international repeaters)")
+	fs.StringVar(&config.SType, "stype", "", "Service type (e.g., GMRS)")
+	fs.StringVar(&config.Lat, "lat", "", "Latitude of the center for a proximity search, in decimal degrees")
+	fs.StringVar(&config.Lon, "lon", "", "Longitude of the center for a proximity search, in decimal degrees")
+	fs.StringVar(&config.Grid, "grid", "", "Maidenhead grid square for a proximity search, instead of --lat/--lon (e.g., FN31pr)")
+	fs.StringVar(&config.Near, "near", "", "Place name to search around, geocoded with OpenStreetMap (e.g., \"Bozeman, MT\")")
+	fs.BoolVar(&config.NearMe, "near-me", false, "Search around your approximate location, based on your IP address")
+	fs.BoolVar(&config.GPS, "gps", false, "Search around the current position reported by a local gpsd daemon")
+	fs.StringVar(&config.GPSDAddr, "gpsd-addr", defaultGPSDAddr, "Address of the gpsd daemon used by --gps")
+	fs.StringVar(&config.Distance, "distance", defaultDistance, "Proximity search radius in mi or km (e.g., 50mi, 80km)")
+	fs.StringVar(&config.MaxDistance, "max-distance", "", "Drop repeaters farther than this from the location, after downloading (e.g., 75mi)")
+	fs.StringVar(&config.Units, "units", "", "Give distances and elevations in metric (km, m) or imperial (mi, ft) units, instead of following --distance")
+	fs.StringVar(&config.From, "from", "", "Measure distances from here without a proximity search: lat,lon, a grid square or a place name")
+	fs.StringVar(&config.CSVDelimiter, "csv-delimiter", "comma", "CSV field delimiter: comma, semicolon, tab, pipe or a single character")
+	fs.StringVar(&config.CSVQuote, "csv-quote", "minimal", "CSV quoting style: minimal (only when needed) or all")
+	fs.BoolVar(&config.CSVBOM, "csv-bom", false, "Start CSV output with a UTF-8 byte order mark")
+	fs.BoolVar(&config.CSVCRLF, "csv-crlf", false, "End CSV lines with CRLF instead of LF")
+	fs.BoolVar(&config.HeaderComment, "header-comment", false, "Start CSV output with # comment lines describing the query, date and rbdl version")
+	fs.BoolVar(&config.SwapRxTx, "swap-rxtx", false, "Swap output and input frequencies to monitor repeater inputs (e.g., foxhunts)")
+	fs.BoolVar(&config.NoInferOffset, "no-infer-offset", false, "Leave missing input frequencies blank instead of filling them in from the band plan")
+	fs.StringVar(&config.BandPlanReport, "band-plan-report", "", "Write every band plan warning to this file, not just the first few on stderr")
+	fs.StringVar(&config.ChannelName, "channel-name", "", "Go text/template for channel names in codeplug exports, e.g. '{{.Callsign}} {{.City | trunc 6}}'")
+	fs.StringVar(&config.NameLength, "name-length", "", "Cut channel names in codeplug exports to this many characters, or to a radio's limit such as uv5r or ft60")
+	fs.IntVar(&config.StartChannel, "start-channel", 1, "First memory number for channels in codeplug exports")
+	fs.IntVar(&config.Bank, "bank", 0, "Put channels in codeplug exports into this bank of --bank-size memories")
+	fs.IntVar(&config.BankSize, "bank-size", defaultBankSize, "Memories per bank for --bank and --bank-bands")
+	fs.StringVar(&config.BankBands, "bank-bands", "", "Banks for each band in codeplug exports, e.g. 2m=2,70cm=3")
+	fs.StringVar(&config.Zones, "zones", "", "Group DMR repeaters into zones by county, city, distance (rings of --zone-ring) or linked system, in a zone field")
+	fs.StringVar(&config.ZoneSize, "zone-size", strconv.Itoa(defaultZoneSize), "Most channels per zone, as a number or a radio such as md380, at878 or opengd77")
+	fs.StringVar(&config.ZoneRing, "zone-ring", defaultZoneRing, "Width of each distance ring for --zones distance (e.g., 25mi, 40km)")
+	fs.StringVar(&config.ScanLists, "scan-lists", "", "Group repeaters into scan lists by band, county, city or linked system, saved beside the output as an AnyTone ScanList.CSV")
+	fs.BoolVar(&config.ExpandTalkgroups, "talkgroups", false, "Expand DMR repeaters' static talkgroups into a contact list and a channel per talkgroup and timeslot, saved beside the output")
+	fs.BoolVar(&config.LinkedSystems, "linked-systems", false, "Name the linked system each repeater appears to belong to, by shared callsign or sponsor, in a linked_system field")
+	fs.BoolVar(&config.AddTalkaround, "add-talkaround", false, "Add a simplex talkaround channel (suffix TA) on each repeater's output in codeplug exports")
+	fs.BoolVar(&config.IncludeWeather, "include-weather", false, "Add the seven NOAA weather broadcast channels, receive only, to codeplug exports of US and Canadian repeaters")
+	fs.BoolVar(&config.IncludeSimplex, "include-simplex", false, "Add the region's calling frequencies and common simplex channels on the bands exported to codeplug exports")
+	fs.StringVar(&config.DualWatchRules, "dual-watch-rules", "", "Rules file pairing repeaters with a secondary channel for dual-watch radios")
+	fs.BoolVar(&config.AutoPower, "auto-power", false, "Set channel power in codeplug exports by distance from the search location: low nearby, high far away")
+	fs.StringVar(&config.PowerThresholds, "power-thresholds", defaultPowerThresholds, "Distances splitting low, mid and high power for --auto-power (e.g., 10mi,25mi, or 15km for low and high only)")
+	fs.StringVar(&config.Encoding, "encoding", "utf-8", "Text encoding for csv, chirp and garmin output: utf-8, windows-1252, iso-8859-1, iso-8859-15 or ascii")
+	fs.StringVar(&config.Coords, "coords", coordsDecimal, "Coordinate format for json, csv, msgpack and template output: decimal, dms or grid (adds a grid field)")
+	fs.StringVar(&config.Endpoint, "endpoint", "auto", "API endpoint: na (North America), row (rest of world) or auto to pick by country")
+	fs.DurationVar(&config.Throttle, "throttle", defaultThrottle, "Pause between API requests when a search needs several")
+	fs.BoolVar(&config.ShowVersion, "version", false, "Print the rbdl version, build details and User-Agent, then exit")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "Print the API requests and output path a download would use, without making any requests")
+	fs.StringVar(&config.Template, "template", "", "Go text/template file rendered once per repeater for --format template")
+	fs.StringVar(&config.Columns, "columns", "", "CSV file of field,column[,default] lines choosing, renaming and ordering CSV output columns")
+	fs.Var(newListFlag(&config.ValueMap), "value-map", "Replace field values in json, csv, msgpack and template output, e.g. \"Operational Status:On-air=ON,FM Analog:Yes=FM\"")
+	fs.BoolVar(&config.PruneEmpty, "prune-empty", false, "Leave out CSV columns that are empty for every repeater")
+	fs.Var(&lineListFlag{value: &config.Match}, "match", "Keep repeaters whose field matches a regular expression, as FIELD=~REGEX or FIELD!~REGEX to exclude (repeatable)")
+	fs.StringVar(&config.Where, "where", "", "Keep repeaters matching an expression over their fields, e.g. 'Frequency >= 144 && Frequency <= 148 && PL != \"\"'")
+	fs.StringVar(&config.Sort, "sort", "", "Sort output by these fields, e.g. State,Frequency or Frequency:desc (default API order)")
+	fs.IntVar(&config.Limit, "limit", 0, "Keep only the first N results, after sorting")
+	fs.IntVar(&config.Sample, "sample", 0, "Keep N results picked at random")
+	fs.IntVar(&config.MaxChannels, "max-channels", 0, "Trim the results to fit a radio with N memories, keeping favorites and then repeaters by --priority")
+	fs.StringVar(&config.Priority, "priority", defaultChannelPriority, "Comma-separated order --max-channels keeps repeaters other than favorites in: closest, on-air, analog, open")
+	fs.Int64Var(&config.Seed, "seed", 0, "Seed for --sample, to pick the same repeaters each run")
+	fs.Var(&previewFlag{&config.Preview}, "preview", "Print the first results as a table instead of saving them, or before saving with --output. Use --preview=N for N rows (default 10)")
+	fs.BoolVar(&config.Quiet, "quiet", false, "Print only errors and warnings, for cron jobs and scripts")
+	fs.BoolVar(&config.Verbose, "verbose", false, "Log each API request's URL, response status, timing and size to stderr")
+	fs.BoolVar(&config.Debug, "debug", false, "Like --verbose, also logging request and response headers")
+	fs.BoolVar(&config.RedactEmail, "redact-email", false, "Replace your email with <email> in --verbose and --debug logs, for sharing them")
+	fs.DurationVar(&config.Timeout, "timeout", defaultTimeout, "Give up on an API request after this long, raise it for large searches")
+	fs.DurationVar(&config.CacheTTL, "cache-ttl", 0, "Reuse cached API responses younger than this (e.g., 30m, 6h), 0 disables the cache")
+	fs.StringVar(&config.CacheDir, "cache-dir", defaultCacheDir(), "Directory for cached API responses")
+	fs.StringVar(&config.Transcript, "transcript", "", "Record the queries, filters and outputs of this run to a JSON file for rbdl replay")
+	fs.StringVar(&config.ArchiveLayout, "archive-layout", "", "File outputs into dated subdirectories: year, year/month or year/month/day")
+	fs.Usage = func() {
+		for i, cmd := range commands {
+			prefix := "Usage: "
+			if i > 0 {
+				prefix = "       "
+			}
+			fmt.Fprintf(os.Stderr, "%s%s\n", prefix, cmd.usage)
+		}
+		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "RepeaterbookDL - Download repeater data from RepeaterBook API\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
+		fs.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --country \"United States\" --mode DMR\n")
 		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --country Canada --mode DMR --format csv\n")
 		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --country \"United States\" --on-air\n")
 		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --output repeaters.csv\n")
+		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --state 30 --on-air --format pdf\n")
+		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --state 30,16,56 --mode DMR\n")
+		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --country \"United States\" --country Canada\n")
 		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --country Mexico --frequency 146.52\n")
 		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --callsign W%%\n")
+		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --lat 45.68 --lon -111.04 --distance 50mi\n")
+		fmt.Fprintf(os.Stderr, "  rbdl --email user@example.com --near \"Bozeman, MT\" --distance 30mi\n")
 		fmt.Fprintf(os.Stderr, "\nNote: Use %% as wildcard for pattern matching\n")
+		fmt.Fprintf(os.Stderr, "Every option can also be set with an RBDL_* environment variable, e.g. RBDL_FORMAT=csv,\n")
+		fmt.Fprintf(os.Stderr, "or in the config file, e.g. format = \"csv\"\n")
 	}
-	flag.Parse()
+	file, err := findConfigFile(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	config.file = file
+	// Flags a subcommand defined before calling us keep their own defaults
+	if config.fileOptions, err = applyConfigFile(fs, file, predefined); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := applyEnvironment(fs, predefined); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if name := argValue(args, "profile"); name != "" || config.Profile != "" {
+		if name == "" {
+			name = config.Profile
+		}
+		if config.profileOptions, err = applyProfile(fs, name, file, predefined); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if name := argValue(args, "preset"); name != "" || config.Preset != "" {
+		if name == "" {
+			name = config.Preset
+		}
+		applied, err := applyPreset(fs, name, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		config.presetOptions = applied
+	}
+	fs.Parse(args)
+	replaceLocation(fs, config)
+	// Below every other source, so a one-off --email or RBDL_EMAIL still wins
+	if config.Email == "" {
+		config.Email = keychainEmail()
+		config.emailFromKeychain = config.Email != ""
+	}
+	config.Encoding = strings.ToLower(config.Encoding)
+	config.Coords = strings.ToLower(config.Coords)
+	config.Units = strings.ToLower(config.Units)
 	// If the format isn't explicitly specified, try to detect it from the output file's extension
 	if config.Format == "" {
-		if config.Output != "" {
+		if config.Template != "" {
+			config.Format = "template"
+		} else if config.Output != "" {
 			ext := strings.ToLower(filepath.Ext(config.Output))
-			if ext == ".csv" {
-				config.Format = "csv"
-			} else if ext == ".json" {
-				config.Format = "json"
+			if format, ok := formatExtensions[ext]; ok {
+				config.Format = format
 			} else {
 				// Default to json for unknown or no extension
 				config.Format = "json"
@@ -108,24 +504,273 @@ func parseFlags() *Config {
 	return config
 }
 
+// Export formats built on CSV share its extension
+func formatExtension(format string) string {
+	if format == "chirp" || format == "garmin" {
+		return ".csv"
+	}
+	if format == "template" {
+		return ".txt"
+	}
+	return "." + format
+}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
 func validateConfig(config *Config) error {
 	if config.Email == "" {
-		return fmt.Errorf("email is required (use --email flag or set a RBDL_EMAIL environment variable)")
+		return fmt.Errorf("email is required (use --email flag, set a RBDL_EMAIL environment variable, add it to the config file or save it with rbdl login)")
+	}
+	return validateOptions(config)
+}
+
+// Checks everything but the email, which only the API needs
+func validateOptions(config *Config) error {
+	if !isOutputFormat(config.Format) {
+		return fmt.Errorf("format must be one of: %s", strings.Join(outputFormats, ", "))
+	}
+	if _, err := parseSortKeys(config.Sort); err != nil {
+		return err
+	}
+	if err := validateReference(config); err != nil {
+		return err
+	}
+	if config.Where != "" {
+		if _, err := compileWhere(config.Where); err != nil {
+			return err
+		}
+	}
+	if _, err := parseMatches(config.Match); err != nil {
+		return err
+	}
+	if err := validateLimits(config); err != nil {
+		return err
+	}
+	if _, err := parseStatusMap(config.StatusMap); err != nil {
+		return err
+	}
+	if _, err := selectedStatuses(config); err != nil {
+		return err
+	}
+	if _, err := selectedUses(config.Use); err != nil {
+		return err
+	}
+	if config.OnlyClub && config.ClubRoster == "" {
+		return fmt.Errorf("--only-club requires a --club-roster")
+	}
+	if config.CallsignPrefix != "" && config.Callsign != "" {
+		return fmt.Errorf("--callsign-prefix can't be combined with --callsign")
+	}
+	if config.CityContains != "" && config.City != "" {
+		return fmt.Errorf("--city-contains can't be combined with --city")
+	}
+	if config.LandmarkContains != "" && config.Landmark != "" {
+		return fmt.Errorf("--landmark-contains can't be combined with --landmark")
+	}
+	if _, _, err := resolveStateIDs(config.StateID, config.Country); err != nil {
+		return err
+	}
+	if _, err := parseBands(config.Band); err != nil {
+		return err
+	}
+	if config.UpdatedSince != "" {
+		if _, err := parseUpdatedSince(config.UpdatedSince); err != nil {
+			return err
+		}
+	}
+	if err := validateToneFilters(config); err != nil {
+		return err
+	}
+	if err := validateModeShortcuts(config); err != nil {
+		return err
+	}
+	if err := validateDMRFilters(config); err != nil {
+		return err
+	}
+	if err := validateDigitalFilters(config); err != nil {
+		return err
+	}
+	if config.FreqMin != "" || config.FreqMax != "" {
+		if config.Frequency != "" {
+			return fmt.Errorf("--frequency can't be combined with --freq-min or --freq-max")
+		}
+		if _, _, err := frequencyRange(config); err != nil {
+			return err
+		}
+	}
+	if err := validateLocation(config); err != nil {
+		return err
+	}
+	if config.AutoPower {
+		if config.Lat == "" && config.Grid == "" && config.Near == "" && !config.NearMe && !config.GPS {
+			return fmt.Errorf("--auto-power requires a location (--lat/--lon, --grid, --near, --near-me or --gps)")
+		}
+		if _, err := parsePowerThresholds(config.PowerThresholds); err != nil {
+			return err
+		}
+	}
+	if _, err := newChannelNamer(config); err != nil {
+		return err
+	}
+	if err := validateMemoryLayout(config); err != nil {
+		return err
+	}
+	if _, err := csvDelimiter(config.CSVDelimiter); err != nil {
+		return err
+	}
+	if config.CSVQuote != "minimal" && config.CSVQuote != "all" {
+		return fmt.Errorf("CSV quoting style must be either 'minimal' or 'all'")
+	}
+	if config.HeaderComment && config.Format != "csv" {
+		return fmt.Errorf("--header-comment is only supported for csv output")
+	}
+	if err := validateAppend(config); err != nil {
+		return err
+	}
+	if err := validateFilenameTemplate(config.FilenameTemplate); err != nil {
+		return err
+	}
+	if err := validateKeep(config); err != nil {
+		return err
+	}
+	if (config.Format == "template") != (config.Template != "") {
+		return fmt.Errorf("--format template and --template must be used together")
+	}
+	if config.Template != "" {
+		if _, err := loadTemplate(config.Template); err != nil {
+			return err
+		}
+	}
+	if config.Columns != "" && config.Format != "csv" {
+		return fmt.Errorf("--columns is only supported for csv output")
+	}
+	if config.PruneEmpty && (config.Format != "csv" || config.Columns != "") {
+		return fmt.Errorf("--prune-empty is only supported for csv output without --columns")
+	}
+	if err := validateUnits(config); err != nil {
+		return err
+	}
+	if err := validateListFiles(config); err != nil {
+		return err
+	}
+	if err := validateMaxChannels(config); err != nil {
+		return err
+	}
+	if err := validateScanLists(config); err != nil {
+		return err
+	}
+	if err := validateZones(config); err != nil {
+		return err
+	}
+	if err := validateTalkgroups(config); err != nil {
+		return err
+	}
+	if err := validateSimplex(config); err != nil {
+		return err
+	}
+	if err := validateWeather(config); err != nil {
+		return err
+	}
+	if err := validateStaticChannels(config); err != nil {
+		return err
+	}
+	if err := validateSplit(config); err != nil {
+		return err
+	}
+	if err := validateCoords(config); err != nil {
+		return err
+	}
+	if err := validateValueMap(config); err != nil {
+		return err
+	}
+	if !isEncoding(config.Encoding) {
+		return fmt.Errorf("encoding must be one of: utf-8, windows-1252, iso-8859-1, iso-8859-15 or ascii")
+	}
+	if config.Encoding != "utf-8" {
+		if config.Format != "csv" && config.Format != "chirp" && config.Format != "garmin" {
+			return fmt.Errorf("encoding can only be changed for csv, chirp and garmin output")
+		}
+		if config.CSVBOM {
+			return fmt.Errorf("a byte order mark can only be written with utf-8 encoding")
+		}
 	}
-	if config.Format != "json" && config.Format != "csv" {
-		return fmt.Errorf("format must be either 'json' or 'csv'")
+	if config.CacheTTL < 0 {
+		return fmt.Errorf("cache TTL can't be negative")
+	}
+	if config.Endpoint != "auto" && config.Endpoint != "na" && config.Endpoint != "row" {
+		return fmt.Errorf("endpoint must be one of 'auto', 'na' or 'row'")
+	}
+	if config.Throttle < 0 {
+		return fmt.Errorf("throttle can't be negative")
+	}
+	if config.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if config.ArchiveLayout != "" {
+		if _, ok := archiveLayouts[config.ArchiveLayout]; !ok {
+			return fmt.Errorf("archive layout must be one of 'year', 'year/month' or 'year/month/day'")
+		}
 	}
 	return nil
 }
 
-func fetchRepeaterData(config *Config) ([]byte, error) {
+// Countries covered by the North America export, everything else is served by the rest of world export
+var northAmericaCountries = map[string]bool{
+	"united states": true,
+	"canada":        true,
+	"mexico":        true,
+}
+
+func queryEndpoint(config *Config) string {
+	switch config.Endpoint {
+	case "na":
+		return apiEndpoint
+	case "row":
+		return rowEndpoint
+	}
+	// Wildcard countries can't be placed, so they stay with the default endpoint
+	country := strings.ToLower(strings.TrimSpace(config.Country))
+	if country != "" && !strings.Contains(country, "%") && !northAmericaCountries[country] {
+		return rowEndpoint
+	}
+	if config.Region != "" && config.Country == "" {
+		return rowEndpoint
+	}
+	return apiEndpoint
+}
+
+// RepeaterBook stores counties without the suffix, e.g. "Gallatin" for Gallatin County
+func countyName(county string) string {
+	county = strings.TrimSpace(county)
+	if strings.HasSuffix(strings.ToLower(county), " county") {
+		county = strings.TrimSpace(county[:len(county)-len(" county")])
+	}
+	return county
+}
+
+// Uses the pattern built from a wildcard helper flag unless it came out empty, i.e. the helper wasn't given
+func wildcardQuery(value, pattern string) string {
+	if strings.Trim(pattern, "%") != "" {
+		return pattern
+	}
+	return value
+}
+
+func buildQueryURL(config *Config) string {
 	// Build query parameters
 	params := url.Values{}
-	if config.Callsign != "" {
-		params.Add("callsign", config.Callsign)
+	if callsign := wildcardQuery(config.Callsign, config.CallsignPrefix+"%"); callsign != "" {
+		params.Add("callsign", callsign)
 	}
-	if config.City != "" {
-		params.Add("city", config.City)
+	if city := wildcardQuery(config.City, "%"+config.CityContains+"%"); city != "" {
+		params.Add("city", city)
 	}
 	if config.Country != "" {
 		params.Add("country", config.Country)
@@ -133,53 +778,96 @@ func fetchRepeaterData(config *Config) ([]byte, error) {
 	if config.Frequency != "" {
 		params.Add("frequency", config.Frequency)
 	}
-	if config.Mode != "" {
-		params.Add("mode", config.Mode)
+	if mode := queryMode(config); mode != "" {
+		params.Add("mode", mode)
 	}
-	if config.Landmark != "" {
-		params.Add("landmark", config.Landmark)
+	if landmark := wildcardQuery(config.Landmark, "%"+config.LandmarkContains+"%"); landmark != "" {
+		params.Add("landmark", landmark)
 	}
-	if config.StateID != "" {
+	endpoint := queryEndpoint(config)
+	// The rest of world export has no notion of US states and Canadian provinces
+	if config.StateID != "" && endpoint == apiEndpoint {
 		params.Add("state_id", config.StateID)
 	}
+	if config.County != "" {
+		params.Add("county", countyName(config.County))
+	}
 	if config.Region != "" {
 		params.Add("region", config.Region)
 	}
 	if config.SType != "" {
 		params.Add("stype", config.SType)
 	}
-	fullURL := apiEndpoint
+	if lat, lon, ok := config.location(); ok {
+		distance, unit := splitDistance(config.Distance)
+		params.Add("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+		params.Add("lng", strconv.FormatFloat(lon, 'f', -1, 64))
+		params.Add("distance", distance)
+		params.Add("Dunit", unit[:1])
+	}
+	fullURL := endpoint
 	if len(params) > 0 {
 		fullURL += "?" + params.Encode()
 	}
-	req, err := http.NewRequest("GET", fullURL, nil)
+	return fullURL
+}
+
+func newAPIRequest(config *Config) (*http.Request, error) {
+	req, err := http.NewRequest("GET", buildQueryURL(config), nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	// User-Agent header format required to authenticate with the API
 	userAgent := fmt.Sprintf(userAgentTemplate, config.Email)
 	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+func fetchRepeaterData(config *Config) ([]byte, error) {
+	req, err := newAPIRequest(config)
+	if err != nil {
+		return nil, err
+	}
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout: config.Timeout,
 	}
+	if config.CacheTTL > 0 {
+		client.Transport = cache.New(config.CacheDir, config.CacheTTL)
+	}
+	var received atomic.Int64
+	if !config.Quiet {
+		stop := startHeartbeat(&received)
+		defer stop()
+	}
+	logRequest(config, req)
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		config.logf("< %v after %s", err, time.Since(start).Round(time.Millisecond))
+		if isTimeout(err) {
+			return nil, timeoutError(config, 0, err)
+		}
 		return nil, fmt.Errorf("making request: %w", err)
 	}
 	defer resp.Body.Close()
+	logResponse(config, resp, time.Since(start))
 	// Check for error status codes
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		// The actual rate limits are unpublished, but forum posts suggest it isn't too forgiving
 		if resp.StatusCode == http.StatusTooManyRequests {
-			return nil, fmt.Errorf("rate limit exceeded (429): too many requests. Wait 10-60 seconds before retrying")
+			return nil, errRateLimited
 		}
 		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(&countingReader{r: resp.Body, n: &received})
 	if err != nil {
+		if isTimeout(err) {
+			return nil, timeoutError(config, received.Load(), err)
+		}
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
+	config.logf("< %s received in %s", formatBytes(int64(len(data))), time.Since(start).Round(time.Millisecond))
 	// Validate the JSON
 	// API responses seem fairly standardized
 	var js json.RawMessage
@@ -189,71 +877,106 @@ func fetchRepeaterData(config *Config) ([]byte, error) {
 	return data, nil
 }
 
-func generateFilename(config *Config) string {
-	timestamp := time.Now().Format("20060102_150405")
-	// Build filename based on search parameters
-	parts := []string{"repeaterbook"}
-	if config.StateID != "" {
-		parts = append(parts, "state_"+config.StateID)
-	}
-	if config.Country != "" {
-		parts = append(parts, "country_"+config.Country)
-	}
-	if config.Mode != "" {
-		parts = append(parts, "mode_"+config.Mode)
-	}
-	if config.Frequency != "" {
-		parts = append(parts, "freq_"+config.Frequency)
-	}
-	filename := parts[0]
-	if len(parts) > 1 {
-		for i := 1; i < len(parts); i++ {
-			filename += "_" + parts[i]
-		}
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "rbdl")
 	}
-	ext := ".json"
-	if config.Format == "csv" {
-		ext = ".csv"
+	return filepath.Join(dir, "rbdl")
+}
+
+// Go time layouts for each supported archive hierarchy
+var archiveLayouts = map[string][]string{
+	"year":           {"2006"},
+	"year/month":     {"2006", "01"},
+	"year/month/day": {"2006", "01", "02"},
+}
+
+// Moves the output file beneath dated directories relative to its own directory, creating them as needed
+func archivePath(path string, layout string, t time.Time) (string, error) {
+	archived := archiveLocation(path, layout, t)
+	if err := os.MkdirAll(filepath.Dir(archived), 0755); err != nil {
+		return "", err
 	}
-	filename += "_" + timestamp + ext
-	return filename
+	return archived, nil
 }
 
-func saveToFile(filepath string, data []byte, config *Config) error {
-	if config.Format == "csv" {
-		return saveToCSV(filepath, data, config.OnAir)
+// Where archivePath files path, without creating the directories
+func archiveLocation(path string, layout string, t time.Time) string {
+	dirs := []string{filepath.Dir(path)}
+	for _, part := range archiveLayouts[layout] {
+		dirs = append(dirs, t.Format(part))
 	}
-	return saveToJSON(filepath, data, config.OnAir)
+	return filepath.Join(append(dirs, filepath.Base(path))...)
 }
 
-func saveToJSON(filepath string, data []byte, onAirOnly bool) error {
-	// If filtering is needed, parse and reconstruct
-	if onAirOnly {
-		records, err := parseJSONToRecords(data, onAirOnly)
+func saveToFile(filepath string, data []byte, config *Config) error {
+	if config.Append {
+		existing, err := loadExistingRecords(filepath, config)
 		if err != nil {
-			return fmt.Errorf("parsing JSON: %w", err)
+			return err
 		}
-		// Reconstruct response with filtered results and updated count
-		response := map[string]interface{}{
-			"count":   len(records),
-			"results": records,
+		appending := *config
+		appending.appendTo = existing
+		config = &appending
+	} else if err := checkOverwrite(filepath, config); err != nil {
+		return err
+	} else {
+		var sidecars []string
+		if config.ScanLists != "" {
+			sidecars = append(sidecars, scanListPath(filepath))
 		}
-		formatted, err := json.MarshalIndent(response, "", "\t")
-		if err != nil {
-			return fmt.Errorf("formatting JSON: %w", err)
+		if config.ExpandTalkgroups {
+			sidecars = append(sidecars, contactListPath(filepath), dmrChannelsPath(filepath))
 		}
-		if err := os.WriteFile(filepath, formatted, 0644); err != nil {
-			return fmt.Errorf("writing file: %w", err)
+		for _, sidecar := range sidecars {
+			if err := checkOverwrite(sidecar, config); err != nil {
+				return err
+			}
 		}
-		return nil
 	}
+	var err error
+	switch config.Format {
+	case "csv":
+		err = saveToCSV(filepath, data, config)
+	case "pdf":
+		err = saveToPDF(filepath, data, config)
+	case "msgpack":
+		err = saveToMessagePack(filepath, data, config)
+	case "chirp":
+		err = saveToCHIRP(filepath, data, config)
+	case "garmin":
+		err = saveToGarmin(filepath, data, config)
+	case "template":
+		err = saveToTemplate(filepath, data, config)
+	default:
+		err = saveToJSON(filepath, data, config)
+	}
+	if err == nil && config.ScanLists != "" {
+		err = saveScanLists(scanListPath(filepath), data, config)
+	}
+	if err == nil && config.ExpandTalkgroups {
+		err = saveTalkgroups(filepath, data, config)
+	}
+	return err
+}
 
-	// No filtering, just pretty print
-	var prettyJSON interface{}
-	if err := json.Unmarshal(data, &prettyJSON); err != nil {
+func saveToJSON(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
 		return fmt.Errorf("parsing JSON: %w", err)
 	}
-	formatted, err := json.MarshalIndent(prettyJSON, "", "\t")
+	if len(records) == 0 {
+		return fmt.Errorf("%w to write", errNoResults)
+	}
+	formatCoordinates(records, config)
+	translateValues(records, config)
+	// Reconstruct response with filtered, normalized results and updated count
+	response := map[string]interface{}{
+		"count":   len(records),
+		"results": records,
+	}
+	formatted, err := json.MarshalIndent(response, "", "\t")
 	if err != nil {
 		return fmt.Errorf("formatting JSON: %w", err)
 	}
@@ -263,7 +986,7 @@ func saveToJSON(filepath string, data []byte, onAirOnly bool) error {
 	return nil
 }
 
-func parseJSONToRecords(data []byte, onAirOnly bool) ([]map[string]interface{}, error) {
+func parseJSONToRecords(data []byte, config *Config) ([]map[string]interface{}, error) {
 	// RepeaterBook API returns: {"count": N, "results": [...]}
 	var response struct {
 		Results []map[string]interface{} `json:"results"`
@@ -272,44 +995,252 @@ func parseJSONToRecords(data []byte, onAirOnly bool) ([]map[string]interface{},
 		return nil, fmt.Errorf("unable to parse API response: %w", err)
 	}
 	if len(response.Results) == 0 {
-		return nil, fmt.Errorf("no results in API response")
+		return nil, fmt.Errorf("%w in API response", errNoResults)
+	}
+	normalizeRecords(response.Results)
+	records := response.Results
+	var err error
+	if config.processed {
+		// Already checked, converted and filtered when they were listed, so only the favorites
+		// marked since and the steps after the static channels run again
+		records, err = prioritizeFavorites(records, config)
+	} else {
+		records, err = prepareRecords(records, config)
+	}
+	if err != nil {
+		return nil, err
 	}
+	if config.appendTo != nil {
+		merged, err := appendRecords(config.appendTo, records, config)
+		if err != nil {
+			return nil, err
+		}
+		// Sorting the merged list again would scatter the favorites
+		if records, err = prioritizeFavorites(merged, config); err != nil {
+			return nil, err
+		}
+	}
+	// After the static channels and saved records join, so all of them have it
+	addInputFrequencies(records, config)
+	if records, err = trimChannels(records, config); err != nil {
+		return nil, err
+	}
+	// Zones and scan lists can be built from the systems
+	assignLinkedSystems(records, config)
+	assignZones(records, config)
+	assignScanLists(records, config)
+	return records, nil
+}
 
-	// Filter for on-air repeaters if requested
-	if onAirOnly {
-		filtered := make([]map[string]interface{}, 0, len(response.Results))
-		for _, record := range response.Results {
-			if status, exists := record["Operational Status"]; exists {
-				if statusStr, ok := status.(string); ok && statusStr == "On-air" {
-					filtered = append(filtered, record)
-				}
-			}
+// Checks, converts and filters downloaded records, then limits them and adds the static channels
+func prepareRecords(records []map[string]interface{}, config *Config) ([]map[string]interface{}, error) {
+	if err := checkBandPlan(records, config); err != nil {
+		return nil, err
+	}
+	// Before the DMR filters, which can then match the networks RadioID fills in
+	if err := checkRadioID(records, config); err != nil {
+		return nil, err
+	}
+	convertElevations(records, config)
+	records, static := setAsideStaticChannels(records)
+	// Matched on the output frequency, so before --swap-rxtx moves it
+	if config.ExcludeFile != "" {
+		excluded, err := loadRepeaterList(config.ExcludeFile, "--exclude-file")
+		if err != nil {
+			return nil, err
+		}
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return !excluded.matches(record)
+		})
+	}
+	var included []map[string]interface{}
+	if config.IncludeFile != "" {
+		list, err := loadRepeaterList(config.IncludeFile, "--include-file")
+		if err != nil {
+			return nil, err
 		}
-		return filtered, nil
+		included = filterRecords(records, list.matches)
+	}
+	if config.SwapRxTx {
+		swapRxTx(records)
 	}
 
-	return response.Results, nil
+	// Filter by operational status if requested
+	if config.OnAir || config.Status != "" {
+		statuses, err := parseStatusMap(config.StatusMap)
+		if err != nil {
+			return nil, err
+		}
+		selected, err := selectedStatuses(config)
+		if err != nil {
+			return nil, err
+		}
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return selected[recordStatus(record, statuses)]
+		})
+	}
+	if config.Band != "" {
+		selected, err := parseBands(config.Band)
+		if err != nil {
+			return nil, err
+		}
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			freq, ok := recordFloat(record, "Frequency")
+			return ok && inBands(freq, selected)
+		})
+	}
+	if config.Use != "" {
+		uses, err := selectedUses(config.Use)
+		if err != nil {
+			return nil, err
+		}
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return uses[strings.ToLower(recordString(record, "Use"))]
+		})
+	}
+	if config.EmergencyPower {
+		records = filterRecords(records, hasEmergencyPower)
+	}
+	if config.UpdatedSince != "" {
+		since, err := parseUpdatedSince(config.UpdatedSince)
+		if err != nil {
+			return nil, err
+		}
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return updatedSince(record, since)
+		})
+	}
+	records = filterTones(records, config)
+	records = filterModes(records, config)
+	records = filterDMR(records, config)
+	records = filterDigital(records, config)
+	for field, required := range map[string]bool{echoLinkField: config.HasEchoLink, irlpField: config.HasIRLP, allStarField: config.HasAllStar} {
+		if required {
+			records = filterRecords(records, func(record map[string]interface{}) bool {
+				return hasLinkNode(record, field)
+			})
+		}
+	}
+	if config.FreqMin != "" || config.FreqMax != "" {
+		low, high, err := frequencyRange(config)
+		if err != nil {
+			return nil, err
+		}
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			freq, ok := recordFloat(record, "Frequency")
+			return ok && freq >= low && freq <= high
+		})
+	}
+	if config.ClubRoster != "" {
+		members, err := loadRoster(config.ClubRoster)
+		if err != nil {
+			return nil, err
+		}
+		tagClubRepeaters(records, members)
+		if config.OnlyClub {
+			records = filterRecords(records, func(record map[string]interface{}) bool {
+				return recordString(record, clubMemberField) != ""
+			})
+		}
+	}
+	if config.Sponsor != "" {
+		// Unlike API searches the text may appear anywhere, since club names vary in their prefixes
+		pattern := wildcardPattern("%" + config.Sponsor + "%")
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return pattern.MatchString(recordString(record, sponsorField))
+		})
+	}
+	if config.Match != "" && len(records) > 0 {
+		matches, err := parseMatches(config.Match)
+		if err != nil {
+			return nil, err
+		}
+		if records, err = filterMatches(records, matches); err != nil {
+			return nil, err
+		}
+	}
+	if config.Where != "" && len(records) > 0 {
+		filter, err := compileWhere(config.Where)
+		if err != nil {
+			return nil, err
+		}
+		if records, err = filterWhere(records, filter); err != nil {
+			return nil, err
+		}
+	}
+	if config.DualWatchRules != "" {
+		rules, err := loadPairingRules(config.DualWatchRules)
+		if err != nil {
+			return nil, err
+		}
+		pairDualWatch(records, rules)
+	}
+	// The API may not honor proximity parameters, so enforce the radius locally as well
+	if lat, lon, ok := config.location(); ok {
+		radius, _ := parseDistance(config.Distance)
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			distance, ok := recordDistance(record, lat, lon)
+			return ok && distance <= radius
+		})
+	}
+	records = restoreIncluded(records, included)
+	if lat, lon, ok := config.referencePoint(); ok {
+		addDistanceFields(records, lat, lon, distanceUnit(config))
+		if config.MaxDistance != "" {
+			limit, _ := parseDistance(config.MaxDistance)
+			records = filterRecords(records, func(record map[string]interface{}) bool {
+				distance, ok := recordDistance(record, lat, lon)
+				return ok && distance <= limit
+			})
+		}
+	}
+	if config.Sort != "" && len(records) > 0 {
+		keys, err := parseSortKeys(config.Sort)
+		if err != nil {
+			return nil, err
+		}
+		if err := sortRecords(records, keys); err != nil {
+			return nil, err
+		}
+	}
+	records, err := prioritizeFavorites(records, config)
+	if err != nil {
+		return nil, err
+	}
+	records = limitRecords(records, config)
+	return addStaticChannels(records, static, config)
 }
 
-func saveToCSV(filepath string, data []byte, onAirOnly bool) error {
-	records, err := parseJSONToRecords(data, onAirOnly)
+func saveToCSV(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
 	if err != nil {
 		return fmt.Errorf("parsing JSON: %w", err)
 	}
 	if len(records) == 0 {
-		return fmt.Errorf("no data to write")
+		return fmt.Errorf("%w to write", errNoResults)
 	}
+	formatCoordinates(records, config)
+	translateValues(records, config)
 	file, err := os.Create(filepath)
 	if err != nil {
 		return fmt.Errorf("creating file: %w", err)
 	}
 	defer file.Close()
-	writer := csv.NewWriter(file)
+	writer, err := newCSVWriter(encodedWriter(file, config.Encoding), config)
+	if err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
 	defer writer.Flush()
-	// Collect all unique headers from all records
+	if config.Columns != "" {
+		return writeMappedCSV(writer, records, config.Columns)
+	}
+	// Collect all unique headers from all records, leaving out those empty in every one with --prune-empty
 	headerSet := make(map[string]bool)
 	for _, record := range records {
-		for key := range record {
+		for key, val := range record {
+			if config.PruneEmpty && (val == nil || recordString(record, key) == "") {
+				continue
+			}
 			headerSet[key] = true
 		}
 	}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A repeatable flag for options whose values may contain commas, such as regular expressions.
+// Values are stored one per line.
+type lineListFlag struct {
+	value       *string
+	fromDefault bool
+}
+
+func (l *lineListFlag) String() string {
+	if l.value == nil {
+		return ""
+	}
+	return *l.value
+}
+
+func (l *lineListFlag) Set(s string) error {
+	if l.fromDefault || *l.value == "" {
+		*l.value = s
+	} else {
+		*l.value += "\n" + s
+	}
+	l.fromDefault = false
+	return nil
+}
+
+func (l *lineListFlag) SetDefault(s string) error {
+	*l.value = s
+	l.fromDefault = true
+	return nil
+}
+
+// A --match filter: a field and the regular expression it must, or with !~ must not, match
+type fieldMatch struct {
+	field   string
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// Parses --match values of the form FIELD=~REGEX or FIELD!~REGEX
+func parseMatches(spec string) ([]fieldMatch, error) {
+	var matches []fieldMatch
+	for _, item := range strings.Split(spec, "\n") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		m := fieldMatch{}
+		field, expr, ok := strings.Cut(item, "=~")
+		if i := strings.Index(item, "!~"); i >= 0 && (!ok || i < len(field)) {
+			field, expr, m.negate = item[:i], item[i+2:], true
+		} else if !ok {
+			return nil, fmt.Errorf("--match %q must be FIELD=~REGEX or FIELD!~REGEX", item)
+		}
+		m.field = strings.TrimSpace(field)
+		if m.field == "" {
+			return nil, fmt.Errorf("--match %q names no field", item)
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("--match %q: %v", item, err)
+		}
+		m.pattern = pattern
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// Keeps records meeting every match. Field names must exist on at least one record.
+func filterMatches(records []map[string]interface{}, matches []fieldMatch) ([]map[string]interface{}, error) {
+	// With nothing to match there are no fields to check the names against
+	if len(records) == 0 {
+		return records, nil
+	}
+	for i, m := range matches {
+		field, ok := findField(records, m.field)
+		if !ok {
+			return nil, fmt.Errorf("--match names a field %s, which no repeater has", m.field)
+		}
+		matches[i].field = field
+	}
+	return filterRecords(records, func(record map[string]interface{}) bool {
+		for _, m := range matches {
+			if m.pattern.MatchString(recordString(record, m.field)) == m.negate {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
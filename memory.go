@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultBankSize = 100
+
+// Parses --bank-bands, e.g. "2m=2,70cm=3", into bank numbers by band name
+func parseBankBands(spec string) (map[string]int, error) {
+	banks := make(map[string]int)
+	if spec == "" {
+		return banks, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		name, number, ok := strings.Cut(strings.TrimSpace(item), "=")
+		band, known := findBand(name)
+		if !ok || !known {
+			return nil, fmt.Errorf("--bank-bands takes BAND=BANK pairs with bands like 2m or 70cm, got %q", item)
+		}
+		bank, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || bank < 1 {
+			return nil, fmt.Errorf("bank for %s must be a positive number", band.Name)
+		}
+		banks[band.Name] = bank
+	}
+	return banks, nil
+}
+
+func validateMemoryLayout(config *Config) error {
+	if config.StartChannel < 0 {
+		return fmt.Errorf("--start-channel can't be negative")
+	}
+	if config.Bank < 0 {
+		return fmt.Errorf("--bank must be a positive number")
+	}
+	if config.BankSize < 1 {
+		return fmt.Errorf("--bank-size must be a positive number")
+	}
+	_, err := parseBankBands(config.BankBands)
+	return err
+}
+
+// Numbers channels into the Location column. Channels go into their band's bank from --bank-bands,
+// else into --bank, else from --start-channel on. Bank N holds the --bank-size memories after those
+// of bank N-1, so with the default size bank 2 is memories 101 to 200.
+func assignLocations(rows []map[string]string, config *Config) error {
+	bankBands, err := parseBankBands(config.BankBands)
+	if err != nil {
+		return err
+	}
+	next := make(map[int]int)
+	counts := make(map[int]int)
+	used := make(map[int]string)
+	for _, row := range rows {
+		bank := config.Bank
+		if freq, err := strconv.ParseFloat(row["Frequency"], 64); err == nil {
+			if b, ok := bankBands[bandForFrequency(freq)]; ok {
+				bank = b
+			}
+		}
+		if _, ok := next[bank]; !ok {
+			if bank == 0 {
+				next[bank] = config.StartChannel
+			} else {
+				next[bank] = (bank-1)*config.BankSize + 1
+			}
+		}
+		if bank != 0 && counts[bank] == config.BankSize {
+			return fmt.Errorf("bank %d holds only %d channels, use --bank-size or spread the bands over more banks", bank, config.BankSize)
+		}
+		location := next[bank]
+		if other, ok := used[location]; ok {
+			return fmt.Errorf("channels %s and %s would both be memory %d, choose banks or a start channel that don't overlap", other, row["Name"], location)
+		}
+		used[location] = row["Name"]
+		row["Location"] = strconv.Itoa(location)
+		next[bank]++
+		counts[bank]++
+	}
+	// Radios list memories in order, so banks read in order too
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, _ := strconv.Atoi(rows[i]["Location"])
+		b, _ := strconv.Atoi(rows[j]["Location"])
+		return a < b
+	})
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Combines saved downloads into one file, dropping repeaters listed in more than one of them
+func runMergeCommand(args []string) int {
+	var inputs []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		inputs = append(inputs, args[0])
+		args = args[1:]
+	}
+	if len(inputs) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl merge input.json input.json... [options]\n")
+		return 1
+	}
+	fs := flag.NewFlagSet("rbdl merge", flag.ExitOnError)
+	config := parseFlags(fs, args)
+	if err := validateOptions(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	responses := make([][]byte, len(inputs))
+	for i, input := range inputs {
+		data, err := readSavedDownload(input, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		responses[i] = data
+	}
+	data, err := mergeResponses(responses)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging %s: %v\n", strings.Join(inputs, ", "), err)
+		return 1
+	}
+	if err := resolveLocation(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving location: %v\n", err)
+		return 1
+	}
+	outputFile := outputPath(config)
+	for _, input := range inputs {
+		if filepath.Clean(outputFile) == filepath.Clean(input) && !config.Append {
+			fmt.Fprintf(os.Stderr, "Error: the merged output would overwrite %s, use --append to merge into it\n", input)
+			return 1
+		}
+	}
+	if err := makeOutputDir(outputFile, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	groups, err := splitResponse(data, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if _, err := saveGroups(groups, suffixGroupPaths(groups, outputFile), config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+// Reads a JSON download, or a CSV written with field names as headers, into the API's response shape
+func readSavedDownload(path string, config *Config) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if strings.ToLower(filepath.Ext(path)) != ".csv" {
+		return data, nil
+	}
+	records, err := parseCSVRecords(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return json.Marshal(map[string]interface{}{
+		"count":   len(records),
+		"results": records,
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import "fmt"
+
+// A digital mode shortcut flag, the record field that marks the mode and the --mode value the API
+// accepts for it, if any
+type modeShortcut struct {
+	flag    string
+	field   string
+	apiMode string
+}
+
+var modeShortcuts = []modeShortcut{
+	{"dstar", "D-Star", ""},
+	{"fusion", "System Fusion", ""},
+	{"nxdn", "NXDN", "NXDN"},
+	{"p25", "APCO P25", "P25"},
+}
+
+// Fields of every digital mode, which an analog-only repeater must not have
+var digitalModeFields = []string{"DMR", "D-Star", "System Fusion", "NXDN", "APCO P25", "Tetra", "M17"}
+
+func (config *Config) modeShortcutSet(flag string) bool {
+	switch flag {
+	case "dstar":
+		return config.DStar
+	case "fusion":
+		return config.Fusion
+	case "nxdn":
+		return config.NXDN
+	case "p25":
+		return config.P25
+	}
+	return false
+}
+
+func selectedModeShortcuts(config *Config) []modeShortcut {
+	var selected []modeShortcut
+	for _, shortcut := range modeShortcuts {
+		if config.modeShortcutSet(shortcut.flag) {
+			selected = append(selected, shortcut)
+		}
+	}
+	return selected
+}
+
+func validateModeShortcuts(config *Config) error {
+	selected := selectedModeShortcuts(config)
+	if len(selected) == 0 && !config.AnalogOnly {
+		return nil
+	}
+	if config.Mode != "" {
+		return fmt.Errorf("--mode can't be combined with --dstar, --fusion, --nxdn, --p25 or --analog-only")
+	}
+	if config.AnalogOnly && len(selected) > 0 {
+		return fmt.Errorf("--analog-only can't be combined with digital mode flags")
+	}
+	return nil
+}
+
+// The mode sent to the API: --mode as given, or the one a single shortcut stands for. Several
+// shortcuts are searched without a mode and told apart afterwards.
+func queryMode(config *Config) string {
+	if config.Mode != "" {
+		return config.Mode
+	}
+	if config.AnalogOnly {
+		return "analog"
+	}
+	if selected := selectedModeShortcuts(config); len(selected) == 1 {
+		return selected[0].apiMode
+	}
+	return ""
+}
+
+// Keeps repeaters carrying any of the selected digital modes, or only analog ones with --analog-only
+func filterModes(records []map[string]interface{}, config *Config) []map[string]interface{} {
+	if config.AnalogOnly {
+		return filterRecords(records, func(record map[string]interface{}) bool {
+			if recordString(record, "FM Analog") != "Yes" {
+				return false
+			}
+			for _, field := range digitalModeFields {
+				if recordString(record, field) == "Yes" {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	selected := selectedModeShortcuts(config)
+	if len(selected) == 0 {
+		return records
+	}
+	return filterRecords(records, func(record map[string]interface{}) bool {
+		for _, shortcut := range selected {
+			if recordString(record, shortcut.field) == "Yes" {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+func saveToMessagePack(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w to write", errNoResults)
+	}
+	formatCoordinates(records, config)
+	translateValues(records, config)
+	// Mirror the API's response shape so consumers can share parsing code with the JSON output
+	response := map[string]interface{}{
+		"count":   float64(len(records)),
+		"results": recordsToValues(records),
+	}
+	var buf bytes.Buffer
+	if err := encodeMessagePack(&buf, response); err != nil {
+		return fmt.Errorf("encoding MessagePack: %w", err)
+	}
+	if err := os.WriteFile(filepath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	return nil
+}
+
+func recordsToValues(records []map[string]interface{}) []interface{} {
+	values := make([]interface{}, len(records))
+	for i, record := range records {
+		values[i] = record
+	}
+	return values
+}
+
+// Encodes the value types produced by encoding/json, using the smallest MessagePack representation for each
+func encodeMessagePack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case float64:
+		// float64(math.MaxInt64) rounds up to 2^63, which doesn't fit in an int64
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			encodeMessagePackInt(buf, int64(v))
+		} else {
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, v)
+		}
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			buf.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			buf.WriteByte(0xd9)
+			buf.WriteByte(byte(n))
+		case n <= math.MaxUint16:
+			buf.WriteByte(0xda)
+			binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(0xdb)
+			binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+		buf.WriteString(v)
+	case []interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			buf.WriteByte(0x90 | byte(n))
+		case n <= math.MaxUint16:
+			buf.WriteByte(0xdc)
+			binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(0xdd)
+			binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+		for _, item := range v {
+			if err := encodeMessagePack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			buf.WriteByte(0x80 | byte(n))
+		case n <= math.MaxUint16:
+			buf.WriteByte(0xde)
+			binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(0xdf)
+			binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+		// Sort keys so repeated downloads produce byte-identical files
+		keys := make([]string, 0, n)
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encodeMessagePack(buf, key)
+			if err := encodeMessagePack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value type %T", value)
+	}
+	return nil
+}
+
+func encodeMessagePackInt(buf *bytes.Buffer, v int64) {
+	switch {
+	case v >= 0 && v <= 127:
+		buf.WriteByte(byte(v))
+	case v < 0 && v >= -32:
+		buf.WriteByte(byte(v))
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(v))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(v))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, v)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// Derived field marking repeaters whose input frequency rbdl filled in from the band plan
+	offsetInferredField = "offset_inferred"
+	// Derived field with the frequency to transmit on, the output plus the offset
+	inputFrequencyField = "input_frequency"
+)
+
+// A stretch of repeater outputs and the offset their inputs use, in MHz
+type offsetSegment struct {
+	min, max float64
+	offset   float64
+}
+
+// Standard repeater sub-bands of the ARRL and RAC band plans, which cover the US and Canada
+var offsetSegments = []offsetSegment{
+	{29.62, 29.68, -0.1},
+	{52.0, 54.0, -1.0},
+	{145.1, 145.5, -0.6},
+	{146.0, 146.4, 0.6},
+	{146.6, 146.995, -0.6},
+	{147.0, 147.4, 0.6},
+	{223.85, 225.0, -1.6},
+	{442.0, 445.0, 5.0},
+	{447.0, 450.0, -5.0},
+	{462.55, 462.725, 5.0},
+	{927.0, 928.0, -25.0},
+	{1282.0, 1294.0, -12.0},
+}
+
+var offsetPlanCountries = map[string]bool{"united states": true, "canada": true}
+
+func standardOffset(freq float64) (float64, bool) {
+	for _, segment := range offsetSegments {
+		if freq >= segment.min-0.0005 && freq <= segment.max+0.0005 {
+			return segment.offset, true
+		}
+	}
+	return 0, false
+}
+
+// The band plan only applies to North American listings, which carry a US state or Canadian province
+func usesOffsetPlan(record map[string]interface{}) bool {
+	if country := recordString(record, "Country"); country != "" {
+		return offsetPlanCountries[strings.ToLower(country)]
+	}
+	return recordString(record, "State ID") != ""
+}
+
+// Fills in missing or zero input frequencies from the band plan, and returns a warning for each
+// listed offset the plan doesn't expect. Cross-band and split repeaters are left alone.
+func checkOffsets(records []map[string]interface{}, config *Config) []string {
+	var warnings []string
+	for _, record := range records {
+		output, ok := recordFloat(record, "Frequency")
+		if !ok || output <= 0 || !usesOffsetPlan(record) {
+			continue
+		}
+		expected, planned := standardOffset(output)
+		if !planned {
+			continue
+		}
+		input, ok := recordFloat(record, "Input Freq")
+		if !ok || input == 0 {
+			if !config.NoInferOffset {
+				record["Input Freq"] = fmt.Sprintf("%.5f", output+expected)
+				record[offsetInferredField] = "yes"
+			}
+			continue
+		}
+		offset := input - output
+		if math.Abs(offset) < 0.0005 || math.Abs(offset) > chirpSplitThreshold {
+			continue
+		}
+		if math.Abs(offset-expected) > 0.0005 {
+			warnings = append(warnings, fmt.Sprintf("%s on %.4f lists an offset of %+.3f MHz, the band plan expects %+.3f", recordString(record, "Callsign"), output, offset, expected))
+		}
+	}
+	return warnings
+}
+
+// Spells out the frequency to transmit on in MHz, the output plus the offset, so programmers and
+// scanners get the pair without working it out. The offset is the listed one, or the band plan's
+// when the input is missing from saved records added after the offsets were checked. It follows
+// --swap-rxtx, and is left out when the offset isn't known.
+func addInputFrequencies(records []map[string]interface{}, config *Config) {
+	for _, record := range records {
+		delete(record, inputFrequencyField)
+		output, ok := recordFloat(record, "Frequency")
+		if !ok || output <= 0 {
+			continue
+		}
+		var offset float64
+		if input, ok := recordFloat(record, "Input Freq"); ok && input > 0 {
+			offset = input - output
+		} else if isStaticChannel(record) {
+			// Hand-written channels without an input are simplex, as CHIRP exports them
+			offset = 0
+		} else if expected, planned := standardOffset(output); planned && usesOffsetPlan(record) && !config.NoInferOffset && !config.SwapRxTx {
+			offset = expected
+		} else {
+			continue
+		}
+		record[inputFrequencyField] = fmt.Sprintf("%.5f", output+offset)
+	}
+}
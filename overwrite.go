@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Existing files are only replaced with --force, or when someone at a terminal agrees to it
+func checkOverwrite(path string, config *Config) error {
+	if config.Force {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	fmt.Fprintf(os.Stderr, "%s already exists. Overwrite it? [y/N] ", path)
+	answer := strings.ToLower(strings.TrimSpace(readLine(os.Stdin)))
+	if answer != "y" && answer != "yes" {
+		return fmt.Errorf("not overwriting %s", path)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Reads a byte at a time, so nothing past the answer is taken from input others read afterwards
+func readLine(r io.Reader) string {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 0 || err != nil || b[0] == '\n' {
+			return string(line)
+		}
+		line = append(line, b[0])
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const dualWatchField = "dual_watch"
+
+// One line of a pairing rules file: repeaters whose field matches pattern are paired with secondary
+type pairingRule struct {
+	field     string
+	pattern   *regexp.Regexp
+	secondary string
+}
+
+// Reads field,pattern,secondary rules, where patterns use the API's % wildcard and # starts a comment
+func loadPairingRules(path string) ([]pairingRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening pairing rules: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	var rules []pairingRule
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading pairing rules: %w", err)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(row[0]), "field") {
+			continue
+		}
+		rules = append(rules, pairingRule{
+			field:     strings.TrimSpace(row[0]),
+			pattern:   wildcardPattern(strings.TrimSpace(row[1])),
+			secondary: strings.TrimSpace(row[2]),
+		})
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules found in %s", path)
+	}
+	return rules, nil
+}
+
+// Compiles a case-insensitive pattern where % matches any run of characters, as in API searches
+func wildcardPattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "%")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?i)^" + strings.Join(parts, ".*") + "$")
+}
+
+// Annotates each record with the secondary channel of the first matching rule. A secondary naming a
+// repeater in the results, such as a linked system's hub, is given with that repeater's frequency.
+func pairDualWatch(records []map[string]interface{}, rules []pairingRule) {
+	byCallsign := make(map[string]map[string]interface{})
+	for _, record := range records {
+		if call := normalizeCallsign(recordString(record, "Callsign")); call != "" {
+			if _, exists := byCallsign[call]; !exists {
+				byCallsign[call] = record
+			}
+		}
+	}
+	for _, record := range records {
+		record[dualWatchField] = ""
+		for _, rule := range rules {
+			if !rule.pattern.MatchString(recordString(record, rule.field)) {
+				continue
+			}
+			secondary := rule.secondary
+			if hub, ok := byCallsign[normalizeCallsign(secondary)]; ok {
+				if hub["Frequency"] == record["Frequency"] && hub["Callsign"] == record["Callsign"] {
+					continue
+				}
+				secondary = strings.TrimSpace(recordString(hub, "Callsign") + " " + recordString(hub, "Frequency"))
+			}
+			record[dualWatchField] = secondary
+			break
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// US Letter, in points. Two columns of monospaced text keep the sheet compact enough for a glovebox.
+const (
+	pdfPageWidth   = 612.0
+	pdfPageHeight  = 792.0
+	pdfMargin      = 36.0
+	pdfFontSize    = 7.5
+	pdfLeading     = 9.0
+	pdfColumns     = 2
+	pdfColumnWidth = 56
+)
+
+type pdfLine struct {
+	text string
+	bold bool
+}
+
+func saveToPDF(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w to write", errNoResults)
+	}
+	doc := renderPDF(cheatSheetTitle(config), buildCheatSheet(records, distanceUnit(config)))
+	if err := os.WriteFile(filepath, doc, 0644); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	return nil
+}
+
+func cheatSheetTitle(config *Config) string {
+	title := "Repeater Cheat Sheet"
+	var filters []string
+	if config.Country != "" {
+		filters = append(filters, config.Country)
+	}
+	if config.StateID != "" {
+		filters = append(filters, "state "+config.StateID)
+	}
+	if config.County != "" {
+		filters = append(filters, countyName(config.County)+" County")
+	}
+	if config.City != "" {
+		filters = append(filters, config.City)
+	}
+	if config.Mode != "" {
+		filters = append(filters, config.Mode)
+	}
+	if len(filters) > 0 {
+		title += " - " + strings.Join(filters, ", ")
+	}
+	return title
+}
+
+// Groups records by band and lays them out as fixed-width rows
+func buildCheatSheet(records []map[string]interface{}, unit string) []pdfLine {
+	grouped := make(map[string][]map[string]interface{})
+	for _, record := range records {
+		freq, _ := recordFloat(record, "Frequency")
+		band := bandForFrequency(freq)
+		grouped[band] = append(grouped[band], record)
+	}
+	bandNames := make([]string, 0, len(grouped))
+	for name := range grouped {
+		bandNames = append(bandNames, name)
+	}
+	sort.Slice(bandNames, func(i, j int) bool {
+		return bandIndex(bandNames[i]) < bandIndex(bandNames[j])
+	})
+	var lines []pdfLine
+	for _, name := range bandNames {
+		group := grouped[name]
+		sort.SliceStable(group, func(i, j int) bool {
+			a, _ := recordFloat(group[i], "Frequency")
+			b, _ := recordFloat(group[j], "Frequency")
+			return a < b
+		})
+		if len(lines) > 0 {
+			lines = append(lines, pdfLine{})
+		}
+		lines = append(lines, pdfLine{text: fmt.Sprintf("%s (%d)", name, len(group)), bold: true})
+		lines = append(lines, pdfLine{text: cheatSheetRow("Freq", "Input", "Offset", "Tone", "Call", "Location"), bold: true})
+		for _, record := range group {
+			lines = append(lines, pdfLine{text: cheatSheetRow(
+				recordString(record, "Frequency"),
+				recordString(record, inputFrequencyField),
+				cheatSheetOffset(record),
+				recordString(record, uplinkToneField),
+				recordString(record, "Callsign"),
+				strings.TrimSpace(cheatSheetLocation(record)+" "+distanceNote(record, unit)),
+			)})
+		}
+	}
+	return lines
+}
+
+func cheatSheetRow(freq, input, offset, tone, call, location string) string {
+	row := fmt.Sprintf("%-9s %-9s %-6s %-6s %-7s %s", truncate(freq, 9), truncate(input, 9), truncate(offset, 6), truncate(tone, 6), truncate(call, 7), location)
+	return truncate(row, pdfColumnWidth)
+}
+
+func cheatSheetOffset(record map[string]interface{}) string {
+	output, ok := recordFloat(record, "Frequency")
+	if !ok {
+		return ""
+	}
+	input, ok := recordFloat(record, "Input Freq")
+	if !ok || input == 0 {
+		return ""
+	}
+	offset := input - output
+	if offset > -0.0005 && offset < 0.0005 {
+		return "simp"
+	}
+	return fmt.Sprintf("%+.3f", offset)
+}
+
+func cheatSheetLocation(record map[string]interface{}) string {
+	city := recordString(record, "Nearest City")
+	state := recordString(record, "State")
+	if city != "" && state != "" {
+		return city + ", " + state
+	}
+	return city + state
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// Paginates lines into columns and serializes a minimal PDF using the built-in Courier fonts
+func renderPDF(title string, lines []pdfLine) []byte {
+	linesPerColumn := int((pdfPageHeight - 2*pdfMargin - 3*pdfLeading) / pdfLeading)
+	columnGap := (pdfPageWidth - 2*pdfMargin) / pdfColumns
+	linesPerPage := linesPerColumn * pdfColumns
+	pageCount := (len(lines) + linesPerPage - 1) / linesPerPage
+	if pageCount == 0 {
+		pageCount = 1
+	}
+	pages := make([]string, 0, pageCount)
+	for page := 0; page < pageCount; page++ {
+		start := page * linesPerPage
+		var content bytes.Buffer
+		writePDFText(&content, pdfMargin, pdfPageHeight-pdfMargin, "F2", 11, title)
+		footer := fmt.Sprintf("Data: RepeaterBook.com - generated %s - page %d of %d", time.Now().Format("2006-01-02"), page+1, pageCount)
+		writePDFText(&content, pdfMargin, pdfMargin/2, "F1", 6, footer)
+		for col := 0; col < pdfColumns; col++ {
+			x := pdfMargin + float64(col)*columnGap
+			y := pdfPageHeight - pdfMargin - 2*pdfLeading
+			for i := 0; i < linesPerColumn; i++ {
+				idx := start + col*linesPerColumn + i
+				if idx >= len(lines) {
+					break
+				}
+				font := "F1"
+				if lines[idx].bold {
+					font = "F2"
+				}
+				writePDFText(&content, x, y, font, pdfFontSize, lines[idx].text)
+				y -= pdfLeading
+			}
+		}
+		pages = append(pages, content.String())
+	}
+	var buf bytes.Buffer
+	var offsets []int
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	buf.WriteString("%PDF-1.4\n")
+	// Objects 1-4 are fixed, each page then takes a page object and a content stream
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+i*2))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+func writePDFText(buf *bytes.Buffer, x, y float64, font string, size float64, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(buf, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapePDFText(text))
+}
+
+// Escapes PDF string delimiters and maps text onto the single-byte font encoding
+func escapePDFText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			sb.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&sb, "\\%03o", r)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Distances splitting low, mid and high power; a single threshold splits low and high only
+const defaultPowerThresholds = "10mi,25mi"
+
+// CHIRP maps these names onto the nearest level the radio supports
+var powerLevelNames = [][]string{
+	1: {"Low", "High"},
+	2: {"Low", "Mid", "High"},
+}
+
+// Parses a comma-separated list of one or two increasing distances into kilometers
+func parsePowerThresholds(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 1 || len(parts) >= len(powerLevelNames) {
+		return nil, fmt.Errorf("power thresholds must be one or two distances, e.g. 10mi,25mi")
+	}
+	thresholds := make([]float64, len(parts))
+	for i, part := range parts {
+		km, err := parseDistance(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid power threshold %q: %w", part, err)
+		}
+		if i > 0 && km <= thresholds[i-1] {
+			return nil, fmt.Errorf("power thresholds must increase, e.g. 10mi,25mi")
+		}
+		thresholds[i] = km
+	}
+	return thresholds, nil
+}
+
+// Picks a power level by distance from the search center, empty when either position is unknown
+func powerLevel(record map[string]interface{}, config *Config) string {
+	lat, lon, ok := config.location()
+	if !ok {
+		return ""
+	}
+	distance, ok := recordDistance(record, lat, lon)
+	if !ok {
+		return ""
+	}
+	thresholds, err := parsePowerThresholds(config.PowerThresholds)
+	if err != nil {
+		return ""
+	}
+	names := powerLevelNames[len(thresholds)]
+	for i, threshold := range thresholds {
+		if distance < threshold {
+			return names[i]
+		}
+	}
+	return names[len(names)-1]
+}
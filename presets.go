@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type preset struct {
+	Description string
+	Options     map[string]string
+}
+
+// Bundles of options for common searches, selected with --preset
+var builtinPresets = map[string]preset{
+	"us-2m-analog": {
+		Description: "Analog FM repeaters on 2m in the United States",
+		Options:     map[string]string{"country": "United States", "band": "2m", "analog-only": "true"},
+	},
+	"us-70cm-analog": {
+		Description: "Analog FM repeaters on 70cm in the United States",
+		Options:     map[string]string{"country": "United States", "band": "70cm", "analog-only": "true"},
+	},
+	"emcomm": {
+		Description: "On-air repeaters with backup power, for emergency communication plans",
+		Options:     map[string]string{"status": "on-air", "emergency-power": "true"},
+	},
+	"travel": {
+		Description: "Open, on-air repeaters for working while on the road",
+		Options:     map[string]string{"status": "on-air", "use": "open"},
+	},
+	"gmrs": {
+		Description: "GMRS repeaters",
+		Options:     map[string]string{"stype": "GMRS"},
+	},
+	"brandmeister": {
+		Description: "DMR repeaters on the BrandMeister network",
+		Options:     map[string]string{"mode": "DMR", "dmr-network": "BrandMeister"},
+	},
+}
+
+// Presets from the config file are listed with the built-in ones, replacing any of the same name
+func allPresets(file *configFile) map[string]preset {
+	presets := make(map[string]preset, len(builtinPresets)+len(file.Presets))
+	for name, p := range builtinPresets {
+		presets[name] = p
+	}
+	for name, p := range file.Presets {
+		presets[name] = p
+	}
+	return presets
+}
+
+func presetNames(presets map[string]preset) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Preset options replace defaults and environment values, while command-line flags still take
+// precedence. Returns the flags that were set.
+func applyPreset(fs *flag.FlagSet, name string, file *configFile) (map[string]bool, error) {
+	presets := allPresets(file)
+	p, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q, expected one of: %s", name, strings.Join(presetNames(presets), ", "))
+	}
+	applied := make(map[string]bool)
+	for option, value := range p.Options {
+		f := fs.Lookup(option)
+		if f == nil || option == "preset" || option == "config" {
+			return nil, fmt.Errorf("preset %s sets unknown option %s", name, option)
+		}
+		if err := setFlagDefault(f, value); err != nil {
+			return nil, fmt.Errorf("preset %s: invalid value %q for --%s: %v", name, value, option, err)
+		}
+		applied[option] = true
+	}
+	return applied, nil
+}
+
+func printPresets(file *configFile) {
+	presets := allPresets(file)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range presetNames(presets) {
+		p := presets[name]
+		options := make([]string, 0, len(p.Options))
+		for option, value := range p.Options {
+			options = append(options, "--"+option+"="+value)
+		}
+		sort.Strings(options)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, p.Description, strings.Join(options, " "))
+	}
+	w.Flush()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+const defaultPreviewRows = 10
+
+// --preview alone shows defaultPreviewRows, --preview=N shows N
+type previewFlag struct {
+	rows *int
+}
+
+func (p *previewFlag) String() string {
+	if p.rows == nil {
+		return "0"
+	}
+	return strconv.Itoa(*p.rows)
+}
+
+func (p *previewFlag) Set(s string) error {
+	switch s {
+	case "true":
+		*p.rows = defaultPreviewRows
+		return nil
+	case "false":
+		*p.rows = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("preview must be a number of rows")
+	}
+	*p.rows = n
+	return nil
+}
+
+func (p *previewFlag) IsBoolFlag() bool { return true }
+
+// Prints the first rows of records as an aligned table of the key columns
+func printPreview(out io.Writer, records []map[string]interface{}, rows int) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, column := range tableColumns {
+		if i > 0 {
+			fmt.Fprintf(w, "\t")
+		}
+		fmt.Fprintf(w, "%s", column.field)
+	}
+	fmt.Fprintln(w)
+	for _, record := range records[:min(rows, len(records))] {
+		for i, column := range tableColumns {
+			if i > 0 {
+				fmt.Fprintf(w, "\t")
+			}
+			fmt.Fprintf(w, "%s", recordString(record, column.field))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	if len(records) > rows {
+		fmt.Fprintf(out, "Showing %d of %d repeaters\n", rows, len(records))
+	}
+}
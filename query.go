@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultThrottle = 3 * time.Second
+
+// A string flag that may be repeated or comma-separated, stored comma-joined in the config
+type listFlag struct {
+	value *string
+	// Values from the environment are replaced, not extended, by the first command-line value
+	fromDefault bool
+}
+
+func newListFlag(value *string) *listFlag {
+	return &listFlag{value: value}
+}
+
+func (l *listFlag) String() string {
+	if l.value == nil {
+		return ""
+	}
+	return *l.value
+}
+
+func (l *listFlag) Set(s string) error {
+	items := splitList(*l.value)
+	if l.fromDefault {
+		items = nil
+		l.fromDefault = false
+	}
+	*l.value = strings.Join(append(items, splitList(s)...), ",")
+	return nil
+}
+
+func (l *listFlag) SetDefault(s string) error {
+	*l.value = strings.Join(splitList(s), ",")
+	l.fromDefault = true
+	return nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// The API takes one state and one country per request, so a search over several becomes one query
+// per combination
+func expandQueries(config *Config) []*Config {
+	states := splitList(config.StateID)
+	countries := splitList(config.Country)
+	if len(states) <= 1 && len(countries) <= 1 {
+		return []*Config{config}
+	}
+	if len(states) == 0 {
+		states = []string{""}
+	}
+	if len(countries) == 0 {
+		countries = []string{""}
+	}
+	queries := make([]*Config, 0, len(states)*len(countries))
+	for _, country := range countries {
+		for _, state := range states {
+			query := *config
+			query.Country = country
+			query.StateID = state
+			queries = append(queries, &query)
+		}
+	}
+	return queries
+}
+
+// Unfiltered searches stop at this many results, see Known API Limitations in the README
+const resultCap = 3500
+
+// Modes the API can search by, used to split a search that hit the result cap
+var apiModes = []string{"analog", "DMR", "NXDN", "P25", "tetra"}
+
+type fetcher struct {
+	throttle   time.Duration
+	transcript *Transcript
+	requests   int
+	// Requests planned so far, which grows when a search is split
+	total int
+}
+
+// Runs each query in turn, pausing between requests to stay clear of the API's rate limits
+func fetchAll(queries []*Config, throttle time.Duration, transcript *Transcript) ([][]byte, error) {
+	f := &fetcher{throttle: throttle, transcript: transcript, total: len(queries)}
+	responses := make([][]byte, 0, len(queries))
+	for i, query := range queries {
+		data, err := f.fetchComplete(query)
+		if err != nil {
+			if len(queries) > 1 {
+				return nil, fmt.Errorf("query %d of %d: %w", i+1, len(queries), err)
+			}
+			return nil, err
+		}
+		responses = append(responses, data...)
+	}
+	return responses, nil
+}
+
+func (f *fetcher) fetch(query *Config) ([]byte, error) {
+	if f.requests > 0 && f.throttle > 0 {
+		time.Sleep(f.throttle)
+	}
+	f.requests++
+	// Runs of several requests report each one, so it's clear which is slow and how far along the run is
+	progress := f.total > 1 && !query.Quiet
+	if progress {
+		fmt.Fprintf(os.Stderr, "Request %d of %d: %s\n", f.requests, f.total, queryDescription(query))
+	}
+	start := time.Now()
+	data, err := fetchRepeaterData(query)
+	if err != nil {
+		return nil, err
+	}
+	if progress {
+		fmt.Fprintf(os.Stderr, "Request %d of %d: %d results, %s in %s\n", f.requests, f.total, responseCount(data), formatBytes(int64(len(data))), time.Since(start).Round(100*time.Millisecond))
+	}
+	f.transcript.addQuery(buildQueryURL(query), data)
+	return data, nil
+}
+
+// Fetches a query, splitting it into narrower ones whenever a response comes back at the result cap
+func (f *fetcher) fetchComplete(query *Config) ([][]byte, error) {
+	data, err := f.fetch(query)
+	if err != nil {
+		return nil, err
+	}
+	if responseCount(data) < resultCap {
+		return [][]byte{data}, nil
+	}
+	parts, by := subdivideQuery(query)
+	if len(parts) == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s returned %d results, the most the API sends, so some may be missing. Narrow the search to get them all\n", buildQueryURL(query), resultCap)
+		return [][]byte{data}, nil
+	}
+	if !query.Quiet {
+		fmt.Fprintf(os.Stderr, "Search hit the %d result cap, splitting it into %d requests by %s\n", resultCap, len(parts), by)
+	}
+	f.total += len(parts)
+	var responses [][]byte
+	for _, part := range parts {
+		partData, err := f.fetchComplete(part)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, partData...)
+	}
+	return responses, nil
+}
+
+func responseCount(data []byte) int {
+	var response struct {
+		Count   int               `json:"count"`
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return 0
+	}
+	if len(response.Results) > response.Count {
+		return len(response.Results)
+	}
+	return response.Count
+}
+
+// Splits a search by state where the country has states, then by mode. Returns nothing once the
+// search can't be narrowed further.
+func subdivideQuery(query *Config) ([]*Config, string) {
+	var parts []*Config
+	if query.StateID == "" && queryEndpoint(query) == apiEndpoint {
+		for _, state := range stateCodes {
+			if strings.EqualFold(state.Country, query.Country) {
+				part := *query
+				part.StateID = state.Code
+				parts = append(parts, &part)
+			}
+		}
+		if len(parts) > 0 {
+			return parts, "state"
+		}
+	}
+	if queryMode(query) == "" && len(selectedModeShortcuts(query)) == 0 {
+		for _, mode := range apiModes {
+			part := *query
+			part.Mode = mode
+			parts = append(parts, &part)
+		}
+		return parts, "mode"
+	}
+	return nil, ""
+}
+
+// Combines several API responses into one, dropping repeaters already seen in an earlier response
+func mergeResponses(responses [][]byte) ([]byte, error) {
+	if len(responses) == 1 {
+		return responses[0], nil
+	}
+	seen := make(map[string]bool)
+	merged := make([]map[string]interface{}, 0)
+	for _, data := range responses {
+		var response struct {
+			Results []map[string]interface{} `json:"results"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, fmt.Errorf("unable to parse API response: %w", err)
+		}
+		for _, record := range response.Results {
+			keys := recordKeys(record)
+			duplicate := false
+			for _, key := range keys {
+				duplicate = duplicate || seen[key]
+				seen[key] = true
+			}
+			if !duplicate {
+				merged = append(merged, record)
+			}
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"count":   len(merged),
+		"results": merged,
+	})
+}
+
+// RepeaterBook IDs are only unique within a state, so both are needed to identify a repeater
+func recordKey(record map[string]interface{}) string {
+	state := recordString(record, "State ID")
+	id := recordString(record, "Rptr ID")
+	if state == "" || id == "" {
+		return ""
+	}
+	return state + "/" + id
+}
+
+// Everything a repeater can be recognized by across queries: its RepeaterBook IDs, and its
+// frequency, callsign and location for listings without IDs or from the other endpoint
+func recordKeys(record map[string]interface{}) []string {
+	var keys []string
+	if key := recordKey(record); key != "" {
+		keys = append(keys, key)
+	}
+	freq, ok := recordFloat(record, "Frequency")
+	call := strings.ToUpper(recordString(record, "Callsign"))
+	if !ok || call == "" {
+		return keys
+	}
+	location := strings.ToLower(recordString(record, "Nearest City") + "," + recordString(record, "State"))
+	if lat, ok := recordFloat(record, "Lat"); ok {
+		if lon, ok := recordFloat(record, "Long"); ok {
+			location = fmt.Sprintf("%.2f,%.2f", lat, lon)
+		}
+	}
+	return append(keys, fmt.Sprintf("%.4f/%s/%s", freq, call, location))
+}
+
+// The parameters that set a query apart from the others in a run
+func queryDescription(query *Config) string {
+	u, err := url.Parse(buildQueryURL(query))
+	if err != nil || u.RawQuery == "" {
+		return "all repeaters"
+	}
+	description, err := url.QueryUnescape(strings.ReplaceAll(u.RawQuery, "&", ", "))
+	if err != nil {
+		return u.RawQuery
+	}
+	return description
+}
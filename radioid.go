@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cartertemm/rbdl/cache"
+)
+
+// RadioID.net publishes its whole repeater database as one file, rebuilt daily
+const (
+	radioIDEndpoint = "https://radioid.net/static/rptrs.json"
+	radioIDCacheTTL = 24 * time.Hour
+)
+
+const (
+	dmrIDField = "DMR ID"
+	// Derived field saying how a DMR repeater compares to its RadioID.net listing
+	radioIDField = "radioid"
+)
+
+// The dump is downloaded at most once a run, however many times records are parsed
+var (
+	radioIDRepeaters []map[string]interface{}
+	radioIDWarned    bool
+)
+
+func loadRadioIDRepeaters(config *Config) ([]map[string]interface{}, error) {
+	if radioIDRepeaters != nil {
+		return radioIDRepeaters, nil
+	}
+	req, err := http.NewRequest("GET", radioIDEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", appUserAgent())
+	client := &http.Client{
+		// The dump runs to several megabytes
+		Timeout:   2 * time.Minute,
+		Transport: cache.New(filepath.Join(config.CacheDir, "radioid"), radioIDCacheTTL),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading the RadioID.net repeater list: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RadioID.net returned status %d", resp.StatusCode)
+	}
+	var dump struct {
+		Rptrs []map[string]interface{} `json:"rptrs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("invalid RadioID.net repeater list: %w", err)
+	}
+	radioIDRepeaters = dump.Rptrs
+	return radioIDRepeaters, nil
+}
+
+// Finds the RadioID.net listing of a repeater by callsign and output frequency, or by callsign
+// alone when RadioID lists only one repeater under it
+func findRadioIDRepeater(record map[string]interface{}, byCall map[string][]map[string]interface{}) map[string]interface{} {
+	listed := byCall[normalizeCallsign(recordString(record, "Callsign"))]
+	freq, ok := recordFloat(record, "Frequency")
+	for _, candidate := range listed {
+		if other, found := recordFloat(candidate, "frequency"); ok && found && math.Abs(other-freq) < 0.001 {
+			return candidate
+		}
+	}
+	if len(listed) == 1 {
+		return listed[0]
+	}
+	return nil
+}
+
+// Cross-references DMR repeaters with RadioID.net, filling in a missing DMR ID and network and
+// noting where the color code or ID disagree in the radioid field
+func checkRadioID(records []map[string]interface{}, config *Config) error {
+	if !config.RadioID {
+		return nil
+	}
+	var dmr []map[string]interface{}
+	for _, record := range records {
+		if recordString(record, "DMR") == "Yes" {
+			dmr = append(dmr, record)
+		}
+	}
+	if len(dmr) == 0 {
+		return nil
+	}
+	listed, err := loadRadioIDRepeaters(config)
+	if err != nil {
+		return err
+	}
+	byCall := make(map[string][]map[string]interface{})
+	for _, repeater := range listed {
+		call := normalizeCallsign(recordString(repeater, "callsign"))
+		byCall[call] = append(byCall[call], repeater)
+	}
+	differing := 0
+	for _, record := range dmr {
+		match := findRadioIDRepeater(record, byCall)
+		if match == nil {
+			record[radioIDField] = "not listed"
+			continue
+		}
+		var differences []string
+		id := recordString(match, "id")
+		switch current := recordString(record, dmrIDField); {
+		case current == "":
+			record[dmrIDField] = id
+		case current != id:
+			differences = append(differences, fmt.Sprintf("DMR ID %s on RadioID", id))
+		}
+		if network := recordString(match, "ipsc_network"); network != "" && recordString(record, dmrNetworkField) == "" {
+			record[dmrNetworkField] = network
+		}
+		listedCC, listedOK := recordFloat(match, "color_code")
+		cc, ok := recordFloat(record, dmrColorCodeField)
+		if listedOK && ok && cc != listedCC {
+			differences = append(differences, fmt.Sprintf("color code %g on RadioID", listedCC))
+		}
+		if len(differences) == 0 {
+			record[radioIDField] = "ok"
+			continue
+		}
+		record[radioIDField] = strings.Join(differences, ", ")
+		differing++
+	}
+	if differing > 0 && !radioIDWarned {
+		radioIDWarned = true
+		fmt.Fprintf(os.Stderr, "Warning: %d DMR repeaters differ from their RadioID.net listing, see the %s field\n", differing, radioIDField)
+	}
+	return nil
+}
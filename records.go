@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func recordString(record map[string]interface{}, key string) string {
+	val, exists := record[key]
+	if !exists || val == nil {
+		return ""
+	}
+	if s, ok := val.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// The API returns most numeric fields as strings, so accept either
+func recordFloat(record map[string]interface{}, key string) (float64, bool) {
+	val, exists := record[key]
+	if !exists || val == nil {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+func filterRecords(records []map[string]interface{}, keep func(map[string]interface{}) bool) []map[string]interface{} {
+	filtered := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		if keep(record) {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// Derived fields added to every record, named in lowercase to set them apart from the API's own fields
+const accessMethodField = "access_method"
+
+const (
+	accessCTCSS     = "ctcss"
+	accessDCS       = "dcs"
+	accessToneBurst = "tone-burst"
+	accessCarrier   = "carrier"
+)
+
+func normalizeRecords(records []map[string]interface{}) {
+	for _, record := range records {
+		record[accessMethodField] = accessMethod(record)
+		uplink, downlink := recordTones(record)
+		record[uplinkToneField] = uplink.String()
+		record[downlinkToneField] = downlink.String()
+	}
+}
+
+const (
+	statusOnAir   = "on-air"
+	statusOffAir  = "off-air"
+	statusTesting = "testing"
+	statusUnknown = "unknown"
+)
+
+// Operational status values seen across API endpoints, keyed by their normalized spelling
+var defaultStatuses = map[string]string{
+	"onair":       statusOnAir,
+	"online":      statusOnAir,
+	"active":      statusOnAir,
+	"operational": statusOnAir,
+	"offair":      statusOffAir,
+	"offline":     statusOffAir,
+	"inactive":    statusOffAir,
+	"down":        statusOffAir,
+	"testing":     statusTesting,
+	"test":        statusTesting,
+	"testmode":    statusTesting,
+	"unknown":     statusUnknown,
+	"":            statusUnknown,
+}
+
+// Reduces a status to lowercase letters and digits so "On-air", "ON AIR" and "on_air" compare equal
+func normalizeStatusKey(status string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(status) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Parses --status-map pairs such as "Temporarily Down=off-air,Beta=testing" on top of the defaults
+func parseStatusMap(s string) (map[string]string, error) {
+	statuses := make(map[string]string, len(defaultStatuses))
+	for key, status := range defaultStatuses {
+		statuses[key] = status
+	}
+	if strings.TrimSpace(s) == "" {
+		return statuses, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		value, status, ok := strings.Cut(pair, "=")
+		status = strings.ToLower(strings.TrimSpace(status))
+		if !ok || !isStatus(status) {
+			return nil, fmt.Errorf("invalid status mapping %q, expected value=status with status one of on-air, off-air, testing or unknown", pair)
+		}
+		statuses[normalizeStatusKey(value)] = status
+	}
+	return statuses, nil
+}
+
+func isStatus(status string) bool {
+	return status == statusOnAir || status == statusOffAir || status == statusTesting || status == statusUnknown
+}
+
+// Parses the --status list, with --on-air standing for --status on-air
+func selectedStatuses(config *Config) (map[string]bool, error) {
+	list := config.Status
+	if config.OnAir {
+		if list != "" {
+			return nil, fmt.Errorf("--on-air is short for --status on-air and can't be combined with --status")
+		}
+		list = statusOnAir
+	}
+	selected := make(map[string]bool)
+	for _, status := range splitList(list) {
+		status = strings.ToLower(status)
+		if !isStatus(status) {
+			return nil, fmt.Errorf("invalid status %q, expected on-air, off-air, testing or unknown", status)
+		}
+		selected[status] = true
+	}
+	return selected, nil
+}
+
+// Statuses missing from the table are treated as unknown rather than silently dropped by a strict comparison
+func recordStatus(record map[string]interface{}, statuses map[string]string) string {
+	if status, ok := statuses[normalizeStatusKey(recordString(record, "Operational Status"))]; ok {
+		return status
+	}
+	return statusUnknown
+}
+
+// Tunes each record to the repeater's input, leaving the tone fields describing the repeater itself
+func swapRxTx(records []map[string]interface{}) {
+	for _, record := range records {
+		input, ok := record["Input Freq"]
+		if !ok || recordString(record, "Input Freq") == "" {
+			continue
+		}
+		record["Input Freq"] = record["Frequency"]
+		record["Frequency"] = input
+	}
+}
+
+// European repeaters are commonly opened with a 1750 Hz tone burst rather than CTCSS. The PL field
+// holds either, so tell them apart here instead of in every export.
+func accessMethod(record map[string]interface{}) string {
+	uplink, _ := recordTones(record)
+	if uplink.kind == accessCarrier && strings.Contains(recordString(record, "Notes"), "1750") {
+		return accessToneBurst
+	}
+	return uplink.kind
+}
+
+// Link system node fields, where an empty value or 0 means the repeater isn't on that system
+const (
+	echoLinkField = "EchoLink Node"
+	irlpField     = "IRLP Node"
+	allStarField  = "AllStar Node"
+)
+
+func hasLinkNode(record map[string]interface{}, field string) bool {
+	node := recordString(record, field)
+	return node != "" && strings.Trim(node, "0") != ""
+}
+
+var repeaterUses = map[string]bool{"open": true, "closed": true, "private": true}
+
+// Parses the --use list against the values of the API's Use field
+func selectedUses(list string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	for _, use := range splitList(list) {
+		use = strings.ToLower(use)
+		if !repeaterUses[use] {
+			return nil, fmt.Errorf("invalid use %q, expected open, closed or private", use)
+		}
+		selected[use] = true
+	}
+	return selected, nil
+}
+
+const emergencyPowerField = "Emergency Power"
+
+// Words trustees use in the notes for backup power when the listing doesn't flag it
+var emergencyPowerKeywords = []string{"emergency power", "backup power", "battery", "generator", "solar"}
+
+func hasEmergencyPower(record map[string]interface{}) bool {
+	if value := recordString(record, emergencyPowerField); value != "" {
+		return strings.EqualFold(value, "Yes")
+	}
+	notes := strings.ToLower(recordString(record, notesField))
+	for _, keyword := range emergencyPowerKeywords {
+		if strings.Contains(notes, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+const lastUpdateField = "Last Update"
+
+func parseUpdatedSince(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("updated-since must be a date such as 2024-01-01")
+	}
+	return t, nil
+}
+
+// Records without a readable update date are treated as never verified
+func updatedSince(record map[string]interface{}, since time.Time) bool {
+	value := recordString(record, lastUpdateField)
+	if len(value) < len("2006-01-02") {
+		return false
+	}
+	updated, err := time.Parse("2006-01-02", value[:len("2006-01-02")])
+	return err == nil && !updated.Before(since)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Added to --output names with --keep, so each run writes a copy of its own
+const rotationTimestamp = "20060102_150405"
+
+func validateKeep(config *Config) error {
+	if config.Keep < 0 {
+		return fmt.Errorf("--keep must be a positive number")
+	}
+	if config.Keep == 0 {
+		return nil
+	}
+	if config.Append {
+		return fmt.Errorf("--keep can't be combined with --append, which keeps a single file")
+	}
+	if config.Output == "" && config.FilenameTemplate != "" && !strings.Contains(config.FilenameTemplate, "{date}") && !strings.Contains(config.FilenameTemplate, "{time}") {
+		return fmt.Errorf("--keep needs {date} or {time} in --filename to tell copies apart")
+	}
+	return nil
+}
+
+// montana.csv becomes montana_20250108_143022.csv
+func timestampedPath(path, timestamp string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + timestamp + ext
+}
+
+func digitPattern(n int) string {
+	return strings.Repeat("[0-9]", n)
+}
+
+// A glob matching every copy of the output, with the timestamps and any archive directories
+// turned into digit wildcards
+func rotationPattern(config *Config) string {
+	var pattern string
+	if config.Output != "" {
+		pattern = outputPath(&Config{Output: config.Output, OutputDir: config.OutputDir})
+		pattern = timestampedPath(pattern, digitPattern(8)+"_"+digitPattern(6))
+	} else {
+		fields := filenameFields(config, time.Now())
+		fields["date"] = digitPattern(8)
+		fields["time"] = digitPattern(6)
+		pattern = expandFilename(config, fields)
+	}
+	if config.ArchiveLayout != "" {
+		dirs := []string{filepath.Dir(pattern)}
+		for _, part := range archiveLayouts[config.ArchiveLayout] {
+			dirs = append(dirs, digitPattern(len(part)))
+		}
+		pattern = filepath.Join(append(dirs, filepath.Base(pattern))...)
+	}
+	return pattern
+}
+
+// Deletes all but the newest --keep copies of the output, going by modification time
+func pruneCopies(config *Config) ([]string, error) {
+	matches, err := filepath.Glob(rotationPattern(config))
+	if err != nil {
+		return nil, fmt.Errorf("finding older copies: %w", err)
+	}
+	type saved struct {
+		path     string
+		modified time.Time
+	}
+	var copies []saved
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		copies = append(copies, saved{path, info.ModTime()})
+	}
+	sort.SliceStable(copies, func(i, j int) bool {
+		if !copies[i].modified.Equal(copies[j].modified) {
+			return copies[i].modified.After(copies[j].modified)
+		}
+		return copies[i].path > copies[j].path
+	})
+	var removed []string
+	for i := config.Keep; i < len(copies); i++ {
+		if err := os.Remove(copies[i].path); err != nil {
+			return removed, fmt.Errorf("removing older copy: %w", err)
+		}
+		removed = append(removed, copies[i].path)
+	}
+	return removed, nil
+}
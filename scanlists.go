@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Derived field naming the scan list a repeater is assigned to, with --scan-lists
+const scanListField = "scan_list"
+
+// AnyTone radios hold up to 50 channels per scan list
+const scanListSize = 50
+
+// Columns of the ScanList.CSV file AnyTone's CPS imports and exports
+var anytoneScanListHeaders = []string{
+	"No.", "Scan List Name", "Scan Channel Member", "Scan Channel Member RX Frequency",
+	"Scan Channel Member TX Frequency", "Scan Mode", "Priority Channel Select", "Priority Channel 1",
+	"Priority Channel 1 RX Frequency", "Priority Channel 1 TX Frequency", "Priority Channel 2",
+	"Priority Channel 2 RX Frequency", "Priority Channel 2 TX Frequency", "Revert Channel",
+	"Look Back Time A[s]", "Look Back Time B[s]", "Dropout Delay Time[s]", "Dwell Time[s]",
+}
+
+func validateScanLists(config *Config) error {
+	if config.ScanLists == "" {
+		return nil
+	}
+	switch config.ScanLists {
+	case "band", "county", "city", "linked":
+	default:
+		return fmt.Errorf("--scan-lists must be one of: band, county, city or linked")
+	}
+	if config.Output == "/dev/stdout" {
+		return fmt.Errorf("--scan-lists writes a second file, give --output a file name rather than stdout")
+	}
+	return nil
+}
+
+func scanListGroup(record map[string]interface{}, by string) string {
+	switch by {
+	case "band":
+		freq, _ := recordFloat(record, "Frequency")
+		return bandForFrequency(freq)
+	case "county":
+		if county := recordString(record, "County"); county != "" {
+			return county
+		}
+	case "city":
+		if city := recordString(record, "Nearest City"); city != "" {
+			return city
+		}
+	case "linked":
+		if system := recordString(record, linkedSystemField); system != "" {
+			return system
+		}
+	}
+	return "Other"
+}
+
+// Groups repeaters into scan lists by band, area or linked system, in the order they're listed, carrying on in
+// numbered lists past the 50 channels a list holds
+func assignScanLists(records []map[string]interface{}, config *Config) {
+	if config.ScanLists == "" {
+		return
+	}
+	counts := make(map[string]int)
+	for _, record := range records {
+		group := scanListGroup(record, config.ScanLists)
+		record[scanListField] = numberedName(group, counts[group], scanListSize)
+		counts[group]++
+	}
+}
+
+// The scan lists go next to the channel export, e.g. montana_scanlists.csv beside montana.csv
+func scanListPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "_scanlists.csv"
+}
+
+// Writes the scan lists in AnyTone's ScanList.CSV layout, naming members as the channel export does
+func saveScanLists(path string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	namer, err := newChannelNamer(config)
+	if err != nil {
+		return err
+	}
+	type scanList struct {
+		name            string
+		members, rx, tx []string
+	}
+	var lists []*scanList
+	byName := make(map[string]*scanList)
+	for _, record := range records {
+		row, ok := chirpRow(record, config)
+		if !ok {
+			continue
+		}
+		name, err := namer.name(record, row["Name"])
+		if err != nil {
+			return err
+		}
+		listName := recordString(record, scanListField)
+		list, ok := byName[listName]
+		if !ok {
+			list = &scanList{name: listName}
+			byName[listName] = list
+			lists = append(lists, list)
+		}
+		rx, _ := recordFloat(record, "Frequency")
+		tx, ok := recordFloat(record, "Input Freq")
+		if !ok || tx <= 0 {
+			tx = rx
+		}
+		list.members = append(list.members, namer.fit(name, ""))
+		list.rx = append(list.rx, fmt.Sprintf("%.5f", rx))
+		list.tx = append(list.tx, fmt.Sprintf("%.5f", tx))
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating scan list file: %w", err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(encodedWriter(file, config.Encoding))
+	defer writer.Flush()
+	if err := writer.Write(anytoneScanListHeaders); err != nil {
+		return fmt.Errorf("writing scan list headers: %w", err)
+	}
+	for i, list := range lists {
+		row := []string{
+			strconv.Itoa(i + 1), list.name, strings.Join(list.members, "|"), strings.Join(list.rx, "|"),
+			strings.Join(list.tx, "|"), "Off", "Off", "Off", "", "", "Off", "", "", "Selected",
+			"2.0", "3.0", "3.1", "3.1",
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("writing scan list: %w", err)
+		}
+	}
+	return nil
+}
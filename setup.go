@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var errSetupAborted = errors.New("setup cancelled")
+
+// Reads answers for the setup questions, asking again until one passes check
+type setupPrompt struct {
+	in  io.Reader
+	out io.Writer
+}
+
+func (p *setupPrompt) ask(question, fallback string, check func(string) error) (string, error) {
+	for {
+		if fallback != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, fallback)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		answer, err := p.readAnswer()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = fallback
+		}
+		if check == nil {
+			return answer, nil
+		}
+		if err := check(answer); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// Reads one line, so end of input cancels rather than taking every default
+func (p *setupPrompt) readAnswer() (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := p.in.Read(b)
+		if n == 0 || err != nil {
+			if len(line) == 0 {
+				return "", errSetupAborted
+			}
+			return strings.TrimSpace(string(line)), nil
+		}
+		if b[0] == '\n' {
+			return strings.TrimSpace(string(line)), nil
+		}
+		line = append(line, b[0])
+	}
+}
+
+// Where a home location answer goes: lat,lon coordinates, a grid square or a place to geocode
+func homeLocation(answer string) map[string]string {
+	if lat, lon, ok := strings.Cut(answer, ","); ok {
+		_, latErr := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		_, lonErr := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if latErr == nil && lonErr == nil {
+			return map[string]string{"lat": strings.TrimSpace(lat), "lon": strings.TrimSpace(lon)}
+		}
+	}
+	if !strings.ContainsAny(answer, " ,") {
+		if _, _, err := gridToLatLon(answer); err == nil {
+			return map[string]string{"grid": answer}
+		}
+	}
+	return map[string]string{"near": answer}
+}
+
+// Asks for the email, home location, bands and format, and returns config file options in the
+// order they were asked. The home search is returned apart, for a profile rather than top-level
+// options that would turn every download into a search around home.
+func runSetupQuestions(p *setupPrompt, storeEmail bool) ([][2]string, [][2]string, string, error) {
+	var options, home [][2]string
+	email, err := p.ask("Email address for the RepeaterBook User-Agent", "", func(s string) error {
+		if !strings.Contains(s, "@") {
+			return fmt.Errorf("RepeaterBook asks for an email address, e.g. you@example.com")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !storeEmail {
+		options = append(options, [2]string{"email", email})
+	}
+	answer, err := p.ask("Home location, as a city, grid square or lat,lon (blank to skip)", "", nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if answer != "" {
+		location := homeLocation(answer)
+		for _, key := range []string{"near", "grid", "lat", "lon"} {
+			if value, ok := location[key]; ok {
+				home = append(home, [2]string{key, value})
+			}
+		}
+		distance, err := p.ask("Search radius around home", defaultDistance, func(s string) error {
+			_, err := parseDistance(s)
+			return err
+		})
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if distance != defaultDistance {
+			home = append(home, [2]string{"distance", distance})
+		}
+	}
+	band, err := p.ask("Preferred bands, e.g. 2m,70cm (blank for all)", "", func(s string) error {
+		if s == "" {
+			return nil
+		}
+		_, err := parseBands(s)
+		return err
+	})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if band != "" {
+		options = append(options, [2]string{"band", band})
+	}
+	format, err := p.ask("Output format: json, csv, pdf, msgpack, chirp or garmin", "json", func(s string) error {
+		if s == "template" || !isOutputFormat(strings.ToLower(s)) {
+			return fmt.Errorf("choose one of json, csv, pdf, msgpack, chirp or garmin")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if format = strings.ToLower(format); format != "json" {
+		options = append(options, [2]string{"format", format})
+	}
+	return options, home, email, nil
+}
+
+func writeSetupConfig(path string, options, home [][2]string) error {
+	var b strings.Builder
+	b.WriteString("# Written by rbdl setup. Keys are flag names, see rbdl fetch --help for the rest.\n")
+	for _, option := range options {
+		fmt.Fprintf(&b, "%s = %s\n", option[0], strconv.Quote(option[1]))
+	}
+	if len(home) > 0 {
+		b.WriteString("\n# Searches around home with rbdl --profile home\n[profiles.home]\n")
+		for _, option := range home {
+			fmt.Fprintf(&b, "%s = %s\n", option[0], strconv.Quote(option[1]))
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+	return nil
+}
+
+func setupConfigPath() string {
+	if path := os.Getenv(envName("config")); path != "" {
+		return path
+	}
+	return defaultConfigPath()
+}
+
+// First run is a bare rbdl at a terminal with nothing configured anywhere
+func needsSetup(args []string) bool {
+	if len(args) > 0 || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return false
+	}
+	if os.Getenv(envName("email")) != "" || os.Getenv(envName("config")) != "" {
+		return false
+	}
+	if path := defaultConfigPath(); path == "" {
+		return false
+	} else if _, err := os.Stat(path); err == nil {
+		return false
+	}
+	return keychainEmail() == ""
+}
+
+func runSetupCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl setup\n")
+		return 1
+	}
+	if !isTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "Error: rbdl setup asks questions, run it at a terminal\n")
+		return 1
+	}
+	path := setupConfigPath()
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "Error: no config directory, set %s to a config file path\n", envName("config"))
+		return 1
+	}
+	if err := checkOverwrite(path, &Config{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	p := &setupPrompt{in: os.Stdin, out: os.Stdout}
+	fmt.Fprintf(p.out, "Let's set up rbdl. Answers are saved to %s, where you can change them later.\n\n", path)
+	k, keychainErr := systemKeychain()
+	storeEmail := false
+	if keychainErr == nil {
+		answer, err := p.ask(fmt.Sprintf("Keep your email in the %s rather than the config file? (y/n)", k.name), "y", nil)
+		if err != nil {
+			fmt.Fprintln(p.out)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		storeEmail = strings.HasPrefix(strings.ToLower(answer), "y")
+	}
+	options, home, email, err := runSetupQuestions(p, storeEmail)
+	if err != nil {
+		fmt.Fprintln(p.out)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if storeEmail {
+		if err := k.set("email", email); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if err := writeSetupConfig(path, options, home); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(p.out, "\nSaved config to %s\n", path)
+	if storeEmail {
+		fmt.Fprintf(p.out, "Saved %s to the %s\n", email, k.name)
+	}
+	if len(home) > 0 {
+		fmt.Fprintf(p.out, "Run rbdl --profile home to download repeaters around home, or give a search such as --state or --near.\n")
+	} else {
+		fmt.Fprintf(p.out, "Run rbdl with a search such as --state or --near to download repeaters with these settings.\n")
+	}
+	return 0
+}
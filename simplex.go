@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A simplex frequency added to codeplug exports with --include-simplex
+type simplexChannel struct {
+	freq    float64
+	name    string
+	comment string
+}
+
+// Calling frequencies and common FM simplex channels of each band plan. Region 3 plans differ by
+// country, so its list follows Australia's, the largest listing there.
+var (
+	northAmericaSimplex = []simplexChannel{
+		{29.6, "10m CALL", "FM simplex calling frequency"},
+		{52.525, "6m CALL", "FM simplex calling frequency"},
+		{146.52, "2m CALL", "National simplex calling frequency"},
+		{146.55, "", "Simplex"},
+		{146.58, "", "Simplex"},
+		{223.5, "220 CALL", "National simplex calling frequency"},
+		{446.0, "70cm CALL", "National simplex calling frequency"},
+		{446.5, "", "Simplex"},
+		{1294.5, "23cm CALL", "National simplex calling frequency"},
+	}
+	simplexPlans = map[string][]simplexChannel{
+		unitedStatesPlan.name: northAmericaSimplex,
+		regionTwoPlan.name:    northAmericaSimplex,
+		regionOnePlan.name: {
+			{29.6, "10m CALL", "FM simplex calling frequency"},
+			{51.51, "6m CALL", "FM simplex calling frequency"},
+			{145.5, "2m CALL", "FM simplex calling frequency (V40)"},
+			{145.525, "", "Simplex (V42)"},
+			{145.55, "", "Simplex (V44)"},
+			{433.5, "70cm CALL", "FM simplex calling frequency (U280)"},
+			{433.525, "", "Simplex (U282)"},
+			{1297.5, "23cm CALL", "FM simplex calling frequency"},
+		},
+		regionThreePlan.name: {
+			{29.6, "10m CALL", "FM simplex calling frequency"},
+			{146.5, "2m CALL", "National simplex calling frequency"},
+			{146.55, "", "Simplex"},
+			{439.0, "70cm CALL", "National simplex calling frequency"},
+		},
+	}
+)
+
+func validateSimplex(config *Config) error {
+	if config.IncludeSimplex && config.Format != "chirp" {
+		return fmt.Errorf("--include-simplex is only supported for chirp output")
+	}
+	return nil
+}
+
+// The simplex channels for the plan of the first repeater, on the bands the export covers: those
+// chosen with --band, or else those the results have repeaters on
+func simplexChannels(records []map[string]interface{}, config *Config) []simplexChannel {
+	if !config.IncludeSimplex || len(records) == 0 {
+		return nil
+	}
+	covered := make(map[string]bool)
+	if selected, err := parseBands(config.Band); err == nil && len(selected) > 0 {
+		for _, band := range selected {
+			covered[band.Name] = true
+		}
+	} else {
+		for _, record := range records {
+			freq, _ := recordFloat(record, "Frequency")
+			covered[bandForFrequency(freq)] = true
+		}
+	}
+	var channels []simplexChannel
+	for _, channel := range simplexPlans[recordBandPlan(records[0]).name] {
+		if covered[bandForFrequency(channel.freq)] {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// CHIRP rows for the simplex channels, named after the frequency when they have no name of their own
+func simplexRows(records []map[string]interface{}, namer *channelNamer, config *Config) []map[string]string {
+	var rows []map[string]string
+	for _, channel := range simplexChannels(records, config) {
+		name := channel.name
+		if name == "" {
+			name = strconv.FormatFloat(channel.freq, 'f', -1, 64)
+		}
+		rows = append(rows, fixedChannelRow(channel.freq, namer.fit(name, ""), channel.comment))
+	}
+	return rows
+}
+
+// A CHIRP row for a channel that isn't a repeater, without tones
+func fixedChannelRow(freq float64, name, comment string) map[string]string {
+	return map[string]string{
+		"Name":         name,
+		"Frequency":    fmt.Sprintf("%.6f", freq),
+		"Offset":       "0.000000",
+		"rToneFreq":    "88.5",
+		"cToneFreq":    "88.5",
+		"DtcsCode":     "023",
+		"DtcsPolarity": "NN",
+		"RxDtcsCode":   "023",
+		"CrossMode":    "Tone->Tone",
+		"Mode":         "FM",
+		"TStep":        "5.00",
+		"Comment":      comment,
+	}
+}
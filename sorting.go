@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type sortKey struct {
+	field      string
+	descending bool
+}
+
+// Parses --sort: comma-separated fields, each optionally suffixed with :asc or :desc
+func parseSortKeys(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, item := range splitList(spec) {
+		field, direction, _ := strings.Cut(item, ":")
+		key := sortKey{field: browseField(strings.TrimSpace(field))}
+		switch strings.ToLower(strings.TrimSpace(direction)) {
+		case "", "asc":
+		case "desc":
+			key.descending = true
+		default:
+			return nil, fmt.Errorf("sort direction for %s must be asc or desc", field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Compares a field of two records, numerically when both values are numbers. Blank values sort
+// last whichever the direction.
+func compareField(left, right map[string]interface{}, field string, descending bool) int {
+	x, y := strings.ToLower(recordString(left, field)), strings.ToLower(recordString(right, field))
+	switch {
+	case x == y:
+		return 0
+	case x == "":
+		return 1
+	case y == "":
+		return -1
+	}
+	result := strings.Compare(x, y)
+	if m, ok := recordFloat(left, field); ok {
+		if n, ok := recordFloat(right, field); ok {
+			switch {
+			case m < n:
+				result = -1
+			case m > n:
+				result = 1
+			default:
+				result = 0
+			}
+		}
+	}
+	if descending {
+		return -result
+	}
+	return result
+}
+
+// Field names must exist on at least one record, so a typo doesn't silently leave the order unchanged
+func sortRecords(records []map[string]interface{}, keys []sortKey) error {
+	for i, key := range keys {
+		field, ok := findField(records, key.field)
+		if !ok {
+			return fmt.Errorf("can't sort by %s, no repeater has that field", key.field)
+		}
+		keys[i].field = field
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, key := range keys {
+			if c := compareField(records[i], records[j], key.field, key.descending); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// Looks up the field a user means, ignoring case and reading underscores as spaces, as in Input_Freq
+func findField(records []map[string]interface{}, name string) (string, bool) {
+	spaced := strings.ReplaceAll(name, "_", " ")
+	for _, record := range records {
+		if _, ok := record[name]; ok {
+			return name, true
+		}
+		for field := range record {
+			if strings.EqualFold(field, name) || strings.EqualFold(field, spaced) {
+				return field, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var splitFields = []string{"band", "mode", "state", "county"}
+
+// Record fields marking each mode, and the name its file gets
+var splitModes = []struct {
+	field, name string
+}{
+	{"FM Analog", "analog"},
+	{"DMR", "dmr"},
+	{"D-Star", "dstar"},
+	{"System Fusion", "fusion"},
+	{"NXDN", "nxdn"},
+	{"APCO P25", "p25"},
+	{"Tetra", "tetra"},
+	{"M17", "m17"},
+}
+
+func validateSplit(config *Config) error {
+	if config.SplitBy == "" {
+		return nil
+	}
+	valid := false
+	for _, field := range splitFields {
+		valid = valid || config.SplitBy == field
+	}
+	if !valid {
+		return fmt.Errorf("--split-by must be one of: %s", strings.Join(splitFields, ", "))
+	}
+	if config.Keep > 0 {
+		return fmt.Errorf("--split-by can't be combined with --keep")
+	}
+	if config.Output == "/dev/stdout" {
+		return fmt.Errorf("--split-by writes several files, give --output a file name rather than stdout")
+	}
+	return nil
+}
+
+// One output file's share of the results, still in the API's response shape so each is saved
+// like a whole download
+type outputGroup struct {
+	name string
+	data []byte
+}
+
+// Splits the merged response by --split-by. Multimode repeaters go in the file of every mode they
+// carry. Without --split-by the response is a single unnamed group.
+func splitResponse(data []byte, config *Config) ([]outputGroup, error) {
+	if config.SplitBy == "" {
+		return []outputGroup{{data: data}}, nil
+	}
+	var response struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("unable to parse API response: %w", err)
+	}
+	states := make(map[string]bool)
+	for _, record := range response.Results {
+		states[recordString(record, "State")] = true
+	}
+	grouped := make(map[string][]map[string]interface{})
+	var names []string
+	for _, record := range response.Results {
+		for _, name := range splitNames(record, config.SplitBy, len(states) > 1) {
+			if _, ok := grouped[name]; !ok {
+				names = append(names, name)
+			}
+			grouped[name] = append(grouped[name], record)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if config.SplitBy == "band" {
+			return bandIndex(names[i]) < bandIndex(names[j])
+		}
+		return names[i] < names[j]
+	})
+	groups := make([]outputGroup, len(names))
+	for i, name := range names {
+		encoded, err := json.Marshal(map[string]interface{}{
+			"count":   len(grouped[name]),
+			"results": grouped[name],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("splitting results: %w", err)
+		}
+		groups[i] = outputGroup{name: name, data: encoded}
+	}
+	return groups, nil
+}
+
+// The groups a repeater belongs to. Counties are prefixed with their state when the results span
+// several, since county names repeat across states.
+func splitNames(record map[string]interface{}, by string, manyStates bool) []string {
+	switch by {
+	case "band":
+		freq, _ := recordFloat(record, "Frequency")
+		return []string{bandForFrequency(freq)}
+	case "mode":
+		var names []string
+		for _, mode := range splitModes {
+			if recordString(record, mode.field) == "Yes" {
+				names = append(names, mode.name)
+			}
+		}
+		if len(names) == 0 {
+			names = append(names, "other")
+		}
+		return names
+	case "state":
+		return []string{groupName(recordString(record, "State"))}
+	}
+	county := groupName(recordString(record, "County"))
+	if manyStates {
+		county = groupName(recordString(record, "State")) + "-" + county
+	}
+	return []string{county}
+}
+
+// Lowercases a group for file names, e.g. "Lewis and Clark" to lewis-and-clark
+func groupName(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.')
+	}), "-")
+}
+
+// Where each group of a download is saved: in place of {group} in a --filename template, or else
+// added to the end of the output file name
+func groupPaths(groups []outputGroup, config *Config) []string {
+	if config.Output != "" || !strings.Contains(config.FilenameTemplate, "{group}") {
+		return suffixGroupPaths(groups, outputPath(config))
+	}
+	paths := make([]string, len(groups))
+	fields := filenameFields(config, time.Now())
+	for i, group := range groups {
+		fields["group"] = strings.ToLower(group.name)
+		paths[i] = expandFilename(config, fields)
+	}
+	return paths
+}
+
+// Adds each group's name to the end of a file name, e.g. montana_2m.csv for montana.csv
+func suffixGroupPaths(groups []outputGroup, path string) []string {
+	paths := make([]string, len(groups))
+	ext := filepath.Ext(path)
+	for i, group := range groups {
+		if group.name == "" {
+			paths[i] = path
+		} else {
+			paths[i] = strings.TrimSuffix(path, ext) + "_" + strings.ToLower(group.name) + ext
+		}
+	}
+	return paths
+}
+
+// Saves each group to its path. A group can lose all its repeaters to the filters, which is only an
+// error when every group does.
+func saveGroups(groups []outputGroup, paths []string, config *Config) ([]string, error) {
+	var saved []string
+	for i, path := range paths {
+		err := saveToFile(path, groups[i].data, config)
+		if errors.Is(err, errNoResults) && len(groups) > 1 {
+			continue
+		}
+		if err != nil {
+			return saved, err
+		}
+		if !config.Quiet {
+			fmt.Printf("Successfully saved data to: %s\n", path)
+			if config.ScanLists != "" {
+				fmt.Printf("Scan lists saved to: %s\n", scanListPath(path))
+			}
+			if config.ExpandTalkgroups {
+				fmt.Printf("Talkgroups saved to: %s and %s\n", contactListPath(path), dmrChannelsPath(path))
+			}
+		}
+		saved = append(saved, path)
+	}
+	if len(saved) == 0 {
+		return nil, fmt.Errorf("%w to write", errNoResults)
+	}
+	return saved, nil
+}
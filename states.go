@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type stateCode struct {
+	Country string
+	Name    string
+	Abbrev  string
+	Code    string
+}
+
+// FIPS codes for US states and territories, and FIPS 10-4 codes for Canadian provinces
+var stateCodes = []stateCode{
+	{"United States", "Alabama", "AL", "01"},
+	{"United States", "Alaska", "AK", "02"},
+	{"United States", "Arizona", "AZ", "04"},
+	{"United States", "Arkansas", "AR", "05"},
+	{"United States", "California", "CA", "06"},
+	{"United States", "Colorado", "CO", "08"},
+	{"United States", "Connecticut", "CT", "09"},
+	{"United States", "Delaware", "DE", "10"},
+	{"United States", "District of Columbia", "DC", "11"},
+	{"United States", "Florida", "FL", "12"},
+	{"United States", "Georgia", "GA", "13"},
+	{"United States", "Hawaii", "HI", "15"},
+	{"United States", "Idaho", "ID", "16"},
+	{"United States", "Illinois", "IL", "17"},
+	{"United States", "Indiana", "IN", "18"},
+	{"United States", "Iowa", "IA", "19"},
+	{"United States", "Kansas", "KS", "20"},
+	{"United States", "Kentucky", "KY", "21"},
+	{"United States", "Louisiana", "LA", "22"},
+	{"United States", "Maine", "ME", "23"},
+	{"United States", "Maryland", "MD", "24"},
+	{"United States", "Massachusetts", "MA", "25"},
+	{"United States", "Michigan", "MI", "26"},
+	{"United States", "Minnesota", "MN", "27"},
+	{"United States", "Mississippi", "MS", "28"},
+	{"United States", "Missouri", "MO", "29"},
+	{"United States", "Montana", "MT", "30"},
+	{"United States", "Nebraska", "NE", "31"},
+	{"United States", "Nevada", "NV", "32"},
+	{"United States", "New Hampshire", "NH", "33"},
+	{"United States", "New Jersey", "NJ", "34"},
+	{"United States", "New Mexico", "NM", "35"},
+	{"United States", "New York", "NY", "36"},
+	{"United States", "North Carolina", "NC", "37"},
+	{"United States", "North Dakota", "ND", "38"},
+	{"United States", "Ohio", "OH", "39"},
+	{"United States", "Oklahoma", "OK", "40"},
+	{"United States", "Oregon", "OR", "41"},
+	{"United States", "Pennsylvania", "PA", "42"},
+	{"United States", "Rhode Island", "RI", "44"},
+	{"United States", "South Carolina", "SC", "45"},
+	{"United States", "South Dakota", "SD", "46"},
+	{"United States", "Tennessee", "TN", "47"},
+	{"United States", "Texas", "TX", "48"},
+	{"United States", "Utah", "UT", "49"},
+	{"United States", "Vermont", "VT", "50"},
+	{"United States", "Virginia", "VA", "51"},
+	{"United States", "Washington", "WA", "53"},
+	{"United States", "West Virginia", "WV", "54"},
+	{"United States", "Wisconsin", "WI", "55"},
+	{"United States", "Wyoming", "WY", "56"},
+	{"United States", "American Samoa", "AS", "60"},
+	{"United States", "Guam", "GU", "66"},
+	{"United States", "Northern Mariana Islands", "MP", "69"},
+	{"United States", "Puerto Rico", "PR", "72"},
+	{"United States", "US Virgin Islands", "VI", "78"},
+	{"Canada", "Alberta", "AB", "01"},
+	{"Canada", "British Columbia", "BC", "02"},
+	{"Canada", "Manitoba", "MB", "03"},
+	{"Canada", "New Brunswick", "NB", "04"},
+	{"Canada", "Newfoundland and Labrador", "NL", "05"},
+	{"Canada", "Nova Scotia", "NS", "07"},
+	{"Canada", "Ontario", "ON", "08"},
+	{"Canada", "Prince Edward Island", "PE", "09"},
+	{"Canada", "Quebec", "QC", "10"},
+	{"Canada", "Saskatchewan", "SK", "11"},
+	{"Canada", "Yukon", "YT", "12"},
+	{"Canada", "Northwest Territories", "NT", "13"},
+	{"Canada", "Nunavut", "NU", "14"},
+}
+
+func normalizeStateName(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, ".", ""))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Translates a comma-separated list of state and province names or abbreviations into codes,
+// leaving numeric codes and wildcard patterns untouched. Canadian and US codes overlap, so names are
+// only matched within the --country given, and the country is returned set to Canada when provinces
+// are named without one.
+func resolveStateIDs(list, country string) (string, string, error) {
+	items := splitList(list)
+	countries := splitList(country)
+	// Wildcard countries could be either, so every state is matched
+	if strings.Contains(country, "%") {
+		countries = nil
+	}
+	var named, first string
+	for i, item := range items {
+		code, in, err := resolveStateID(item, countries)
+		if err != nil {
+			return "", "", err
+		}
+		items[i] = code
+		// Codes given without a country are searched as US states
+		if in == "" && country == "" && !strings.Contains(item, "%") {
+			in = "United States"
+		}
+		if in == "" {
+			continue
+		}
+		if named != "" && in != named {
+			return "", "", fmt.Errorf("state %q is in %s but %q is in %s, search them separately", first, countryName(named), item, countryName(in))
+		}
+		if named == "" {
+			named, first = in, item
+		}
+	}
+	if named == "Canada" && country == "" {
+		country = named
+	}
+	return strings.Join(items, ","), country, nil
+}
+
+// Returns the code for a state name or abbreviation and the country it's in, looking only in
+// countries when any are given
+func resolveStateID(name string, countries []string) (string, string, error) {
+	if strings.Contains(name, "%") || strings.Trim(name, "0123456789") == "" {
+		return name, "", nil
+	}
+	if len(countries) > 1 {
+		return "", "", fmt.Errorf("state %q can't be combined with more than one --country, as each state is searched in every country", name)
+	}
+	var candidates []stateCode
+	for _, state := range stateCodes {
+		if len(countries) == 0 || strings.EqualFold(state.Country, countries[0]) {
+			candidates = append(candidates, state)
+		}
+	}
+	code, err := matchState(name, candidates)
+	if err == nil {
+		return code.Code, code.Country, nil
+	}
+	if len(countries) > 0 {
+		// Name the right country when the state is elsewhere
+		if other, otherErr := matchState(name, stateCodes); otherErr == nil {
+			return "", "", fmt.Errorf("state %q is in %s, not %s", name, countryName(other.Country), countries[0])
+		}
+	}
+	return "", "", err
+}
+
+func countryName(country string) string {
+	if country == "United States" {
+		return "the United States"
+	}
+	return country
+}
+
+func matchState(name string, candidates []stateCode) (stateCode, error) {
+	key := normalizeStateName(name)
+	var matches []string
+	var match stateCode
+	for _, state := range candidates {
+		if key == normalizeStateName(state.Name) || key == strings.ToLower(state.Abbrev) {
+			return state, nil
+		}
+		// A unique prefix such as "Sask" is accepted, anything else is only suggested
+		if strings.HasPrefix(normalizeStateName(state.Name), key) {
+			matches = append(matches, state.Name)
+			match = state
+		}
+	}
+	if len(matches) == 1 {
+		return match, nil
+	}
+	if len(matches) > 1 {
+		return stateCode{}, fmt.Errorf("state %q is ambiguous, it could be %s", name, strings.Join(matches, ", "))
+	}
+	for _, state := range candidates {
+		if levenshtein(key, normalizeStateName(state.Name)) <= 2 {
+			matches = append(matches, state.Name)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) > 0 {
+		return stateCode{}, fmt.Errorf("unknown state %q, did you mean %s?", name, strings.Join(matches, ", "))
+	}
+	return stateCode{}, fmt.Errorf("unknown state %q, use a state or province name, a postal abbreviation or a FIPS code", name)
+}
+
+// Edit distance between two strings, for suggesting names close to a misspelling
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// Derived field marking the channels read from --static-channels
+const staticChannelField = "static_channel"
+
+// Reads the --static-channels CSV, which uses field names as headers like a saved CSV download so
+// the channels pass through every output the same way repeaters do
+func loadStaticChannels(path string, config *Config) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening --static-channels: %w", err)
+	}
+	records, err := parseCSVRecords(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("reading --static-channels: %w", err)
+	}
+	for i, record := range records {
+		if freq, ok := recordFloat(record, "Frequency"); !ok || freq <= 0 {
+			return nil, fmt.Errorf("--static-channels row %d: missing Frequency", i+1)
+		}
+		// Hand-written channels are usually simplex FM, so that's assumed unless a mode is given
+		if _, ok := record["FM Analog"]; !ok && recordString(record, "DMR") != "Yes" && recordString(record, "D-Star") != "Yes" {
+			record["FM Analog"] = "Yes"
+		}
+		record[staticChannelField] = "yes"
+	}
+	return records, nil
+}
+
+func validateStaticChannels(config *Config) error {
+	if config.StaticChannels == "" {
+		return nil
+	}
+	_, err := loadStaticChannels(config.StaticChannels, config)
+	return err
+}
+
+func isStaticChannel(record map[string]interface{}) bool {
+	return recordString(record, staticChannelField) == "yes"
+}
+
+// A static channel and a download are the same when they share output and input frequencies, and
+// callsigns when both have one
+func sameChannel(static, record map[string]interface{}) bool {
+	output, _ := recordFloat(static, "Frequency")
+	other, ok := recordFloat(record, "Frequency")
+	if !ok || math.Abs(output-other) > 0.0005 {
+		return false
+	}
+	input, ok := recordFloat(static, "Input Freq")
+	if !ok || input <= 0 {
+		input = output
+	}
+	otherInput, ok := recordFloat(record, "Input Freq")
+	if !ok || otherInput <= 0 {
+		otherInput = other
+	}
+	if math.Abs(input-otherInput) > 0.0005 {
+		return false
+	}
+	call, otherCall := normalizeCallsign(recordString(static, "Callsign")), normalizeCallsign(recordString(record, "Callsign"))
+	return call == "" || otherCall == "" || call == otherCall
+}
+
+// Static channels already in the data, from a saved download or a browse export, skip the filters
+// as they did when they were first added
+func setAsideStaticChannels(records []map[string]interface{}) (rest, static []map[string]interface{}) {
+	for _, record := range records {
+		if isStaticChannel(record) {
+			static = append(static, record)
+		} else {
+			rest = append(rest, record)
+		}
+	}
+	return rest, static
+}
+
+// Adds the --static-channels, and those set aside, to the results after filtering and --limit so
+// every export gets them. One that's already downloaded is merged into that listing, its own values
+// winning, and the rest go at the end in the file's order.
+func addStaticChannels(records, carried []map[string]interface{}, config *Config) ([]map[string]interface{}, error) {
+	records = append(records, carried...)
+	changed := append([]map[string]interface{}(nil), carried...)
+	if config.StaticChannels != "" {
+		static, err := loadStaticChannels(config.StaticChannels, config)
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range static {
+			merged := false
+			for _, record := range records {
+				if sameChannel(channel, record) {
+					for field, value := range channel {
+						record[field] = value
+					}
+					changed = append(changed, record)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				records = append(records, channel)
+				changed = append(changed, channel)
+			}
+		}
+	}
+	// Tones and distances are derived again from the merged values
+	normalizeRecords(changed)
+	if lat, lon, ok := config.referencePoint(); ok {
+		addDistanceFields(changed, lat, lon, distanceUnit(config))
+	}
+	return records, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Columns of the TalkGroups.CSV contact list AnyTone's CPS imports and exports
+var anytoneContactHeaders = []string{"No.", "Radio ID", "Name", "Call Type", "Call Alert"}
+
+// The leading columns of AnyTone's Channel.CSV, the rest being left for the CPS to default
+var anytoneChannelHeaders = []string{
+	"No.", "Channel Name", "Receive Frequency", "Transmit Frequency", "Channel Type", "Transmit Power",
+	"Band Width", "CTCSS/DCS Decode", "CTCSS/DCS Encode", "Contact", "Contact Call Type",
+	"Contact TG/DMR ID", "Radio ID", "Busy Lock/TX Permit", "Squelch Mode", "Optional Signal", "DTMF ID",
+	"2Tone ID", "5Tone ID", "PTT ID", "Color Code", "Slot", "Scan List", "Receive Group List",
+}
+
+// Contact names are limited to 16 characters, and channel names keep room for the callsign
+const (
+	contactNameLength    = 16
+	talkgroupLabelLength = 8
+)
+
+// Trustees list static talkgroups in the notes after a timeslot, e.g. "TS1: 91 Worldwide, 3100 USA;
+// TS2: 3130 Montana"
+var (
+	timeslotMarker = regexp.MustCompile(`(?i)\b(?:TS|time ?slot|slot)\s*([12])\b\s*[:=-]?`)
+	talkgroupEntry = regexp.MustCompile(`(?i)^(?:TG\s*#?\s*)?(\d{1,8})\b\s*[-:=]?\s*(.*)$`)
+)
+
+type talkgroup struct {
+	id   int
+	name string
+	slot int
+}
+
+// Reads the static talkgroups listed in a DMR repeater's notes. Numbers outside a timeslot are
+// left alone, as they're as likely to be a node number or a phone number.
+func recordTalkgroups(record map[string]interface{}) []talkgroup {
+	notes := recordString(record, notesField)
+	markers := timeslotMarker.FindAllStringSubmatchIndex(notes, -1)
+	var talkgroups []talkgroup
+	seen := make(map[[2]int]bool)
+	for i, marker := range markers {
+		end := len(notes)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		slot, _ := strconv.Atoi(notes[marker[2]:marker[3]])
+		for _, entry := range strings.FieldsFunc(notes[marker[1]:end], func(r rune) bool {
+			return r == ',' || r == ';' || r == '\n' || r == '|'
+		}) {
+			match := talkgroupEntry.FindStringSubmatch(strings.TrimSpace(entry))
+			if match == nil {
+				continue
+			}
+			id, err := strconv.Atoi(match[1])
+			if err != nil || id <= 0 || seen[[2]int{slot, id}] {
+				continue
+			}
+			seen[[2]int{slot, id}] = true
+			// A sentence may carry on after the last talkgroup
+			name, _, _ := strings.Cut(match[2], ". ")
+			talkgroups = append(talkgroups, talkgroup{id: id, name: strings.Trim(name, " -:()."), slot: slot})
+		}
+	}
+	return talkgroups
+}
+
+// The contact list and channels go next to the export, e.g. montana_contacts.csv and
+// montana_dmr_channels.csv beside montana.csv
+func contactListPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "_contacts.csv"
+}
+
+func dmrChannelsPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "_dmr_channels.csv"
+}
+
+func validateTalkgroups(config *Config) error {
+	if config.ExpandTalkgroups && config.Output == "/dev/stdout" {
+		return fmt.Errorf("--talkgroups writes two more files, give --output a file name rather than stdout")
+	}
+	return nil
+}
+
+// Writes a contact per talkgroup and a channel per repeater, talkgroup and timeslot, in AnyTone's
+// TalkGroups.CSV and Channel.CSV layouts, as a DMR codeplug is built
+func saveTalkgroups(path string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	namer, err := newChannelNamer(config)
+	if err != nil {
+		return err
+	}
+	if namer.limit == 0 {
+		namer.limit = zoneNameLength
+	}
+	contacts := make(map[int]string)
+	var order []int
+	taken := make(map[string]bool)
+	contact := func(tg talkgroup) string {
+		if name, ok := contacts[tg.id]; ok {
+			return name
+		}
+		// Contact names must be unique, so talkgroups sharing a name are told apart by number
+		name := truncate(tg.name, contactNameLength)
+		if name == "" {
+			name = "TG " + strconv.Itoa(tg.id)
+		} else if taken[strings.ToLower(name)] {
+			name = strings.TrimSpace(truncate(tg.name, contactNameLength-len(strconv.Itoa(tg.id))-1) + " " + strconv.Itoa(tg.id))
+		}
+		taken[strings.ToLower(name)] = true
+		contacts[tg.id] = name
+		order = append(order, tg.id)
+		return name
+	}
+	var channels [][]string
+	for _, record := range records {
+		if recordString(record, "DMR") != "Yes" {
+			continue
+		}
+		rx, ok := recordFloat(record, "Frequency")
+		if !ok || rx <= 0 {
+			continue
+		}
+		tx, ok := recordFloat(record, "Input Freq")
+		if !ok || tx <= 0 {
+			tx = rx
+		}
+		base, err := namer.name(record, recordString(record, "Callsign"))
+		if err != nil {
+			return err
+		}
+		colorCode := "1"
+		if cc, ok := recordFloat(record, dmrColorCodeField); ok {
+			colorCode = strconv.Itoa(int(cc))
+		}
+		for _, tg := range recordTalkgroups(record) {
+			name := contact(tg)
+			channels = append(channels, []string{
+				strconv.Itoa(len(channels) + 1), namer.fit(base, " "+truncate(name, talkgroupLabelLength)),
+				fmt.Sprintf("%.5f", rx), fmt.Sprintf("%.5f", tx), "D-Digital", "High", "12.5K", "Off", "Off",
+				name, "Group Call", strconv.Itoa(tg.id), "", "Always", "Carrier", "Off", "1", "1", "1", "Off",
+				colorCode, strconv.Itoa(tg.slot), "None", "None",
+			})
+		}
+	}
+	rows := make([][]string, len(order))
+	for i, id := range order {
+		rows[i] = []string{strconv.Itoa(i + 1), strconv.Itoa(id), contacts[id], "Group Call", "None"}
+	}
+	if err := writeCSVFile(contactListPath(path), anytoneContactHeaders, rows, config); err != nil {
+		return fmt.Errorf("writing contact list: %w", err)
+	}
+	if err := writeCSVFile(dmrChannelsPath(path), anytoneChannelHeaders, channels, config); err != nil {
+		return fmt.Errorf("writing DMR channels: %w", err)
+	}
+	return nil
+}
+
+func writeCSVFile(path string, headers []string, rows [][]string, config *Config) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(encodedWriter(file, config.Encoding))
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Functions available to --template files
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	// Arguments come before the piped value, as in {{.Callsign | trunc 6}}
+	"trunc": func(n int, s string) string {
+		return truncate(s, n)
+	},
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+	"default": func(fallback, s string) string {
+		if s == "" {
+			return fallback
+		}
+		return s
+	},
+}
+
+// Templates see each field as a string, so missing and null fields are empty rather than "<no value>"
+func templateRecord(record map[string]interface{}) map[string]string {
+	fields := make(map[string]string, len(record))
+	for key := range record {
+		fields[key] = recordString(record, key)
+	}
+	return fields
+}
+
+func loadTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Option("missingkey=zero").ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Renders the template once per record. Templates defined as "header" and "footer" are rendered
+// once before and after the records, with the list of all records.
+func saveToTemplate(filepath string, data []byte, config *Config) error {
+	records, err := parseJSONToRecords(data, config)
+	if err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w to write", errNoResults)
+	}
+	formatCoordinates(records, config)
+	translateValues(records, config)
+	tmpl, err := loadTemplate(config.Template)
+	if err != nil {
+		return err
+	}
+	all := make([]map[string]string, len(records))
+	for i, record := range records {
+		all[i] = templateRecord(record)
+	}
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	if header := tmpl.Lookup("header"); header != nil {
+		if err := header.Execute(w, all); err != nil {
+			return fmt.Errorf("rendering header: %w", err)
+		}
+	}
+	for _, record := range all {
+		if err := tmpl.Execute(w, record); err != nil {
+			return fmt.Errorf("rendering %s: %w", record["Callsign"], err)
+		}
+	}
+	if footer := tmpl.Lookup("footer"); footer != nil {
+		if err := footer.Execute(w, all); err != nil {
+			return fmt.Errorf("rendering footer: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	return nil
+}
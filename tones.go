@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Derived fields holding each tone in canonical syntax
+const (
+	uplinkToneField   = "uplink_tone"
+	downlinkToneField = "downlink_tone"
+)
+
+// One tone setting, however the API spelled it: "100", "100.0 Hz", "D023", "023 DCS", "D023I", "CSQ"
+// or blank. Kind is one of the access methods.
+type tone struct {
+	kind     string
+	hz       float64
+	code     string
+	inverted bool
+}
+
+func parseTone(raw string) tone {
+	s := strings.TrimSpace(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(raw)), "HZ"))
+	switch {
+	case strings.Contains(s, "1750"):
+		return tone{kind: accessToneBurst, hz: 1750}
+	case strings.Contains(s, "D"):
+		digits := strings.TrimFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if n, err := strconv.Atoi(digits); err == nil && strings.Trim(digits, "01234567") == "" {
+			return tone{kind: accessDCS, code: fmt.Sprintf("%03d", n), inverted: strings.HasSuffix(s, "I") || strings.Contains(s, "INV")}
+		}
+		return tone{kind: accessCarrier}
+	}
+	if hz, err := strconv.ParseFloat(s, 64); err == nil && hz >= 60 && hz <= 260 {
+		return tone{kind: accessCTCSS, hz: hz}
+	}
+	return tone{kind: accessCarrier}
+}
+
+// Canonical syntax: 100.0 for CTCSS, D023N or D023I for DCS, 1750 for a tone burst and nothing for carrier
+func (t tone) String() string {
+	switch t.kind {
+	case accessCTCSS:
+		return strconv.FormatFloat(t.hz, 'f', 1, 64)
+	case accessDCS:
+		return "D" + t.code + t.polarity()
+	case accessToneBurst:
+		return "1750"
+	}
+	return ""
+}
+
+// N for normal or I for inverted DCS
+func (t tone) polarity() string {
+	if t.inverted {
+		return "I"
+	}
+	return "N"
+}
+
+func (t tone) equal(other tone) bool {
+	return t.kind == other.kind && math.Abs(t.hz-other.hz) < 0.05 && t.code == other.code && t.inverted == other.inverted
+}
+
+// The tone users transmit to open the repeater, and the one it transmits for squelching on
+func recordTones(record map[string]interface{}) (tone, tone) {
+	return parseTone(recordString(record, "PL")), parseTone(recordString(record, "TSQ"))
+}
+
+// DCS codes are listed as 023, D023 or DCS 023, reduce them to the bare digits
+func dcsCode(s string) string {
+	code := strings.TrimLeft(strings.ToUpper(strings.TrimSpace(s)), "DCS ")
+	code = strings.TrimRight(code, "NI")
+	if n, err := strconv.Atoi(code); err == nil {
+		return fmt.Sprintf("%03d", n)
+	}
+	return code
+}
+
+func validateToneFilters(config *Config) error {
+	if config.Tone != "" {
+		if tone, err := strconv.ParseFloat(config.Tone, 64); err != nil || tone < 60 || tone > 260 {
+			return fmt.Errorf("tone must be a CTCSS frequency in Hz, e.g. 100.0")
+		}
+	}
+	if config.DCS != "" {
+		code := dcsCode(config.DCS)
+		if len(code) != 3 || strings.Trim(code, "01234567") != "" {
+			return fmt.Errorf("DCS code must be three octal digits, e.g. 023")
+		}
+	}
+	if config.Tone != "" && config.DCS != "" {
+		return fmt.Errorf("--tone can't be combined with --dcs")
+	}
+	return nil
+}
+
+// Matches the uplink or downlink tone, since either can be what a radio needs programmed
+func toneMatches(record map[string]interface{}, hz float64) bool {
+	uplink, downlink := recordTones(record)
+	for _, t := range []tone{uplink, downlink} {
+		if t.kind == accessCTCSS && math.Abs(t.hz-hz) < 0.05 {
+			return true
+		}
+	}
+	return false
+}
+
+func dcsMatches(record map[string]interface{}, code string) bool {
+	uplink, downlink := recordTones(record)
+	for _, t := range []tone{uplink, downlink} {
+		if t.kind == accessDCS && t.code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func filterTones(records []map[string]interface{}, config *Config) []map[string]interface{} {
+	if config.Tone != "" {
+		tone, _ := strconv.ParseFloat(config.Tone, 64)
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return toneMatches(record, tone)
+		})
+	}
+	if config.DCS != "" {
+		code := dcsCode(config.DCS)
+		records = filterRecords(records, func(record map[string]interface{}) bool {
+			return dcsMatches(record, code)
+		})
+	}
+	return records
+}
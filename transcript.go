@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const transcriptVersion = 1
+
+// A replay served from the cache should accept cached responses of any age
+const replayCacheTTL = 100 * 365 * 24 * time.Hour
+
+// Options that change which records end up in the output, listed separately in transcripts
+var filterFlags = []string{"on-air", "status", "use", "emergency-power", "updated-since", "tone", "dcs", "dstar", "fusion", "nxdn", "p25", "analog-only", "dmr-network", "color-code", "talkgroup", "nxdn-ran", "p25-nac", "has-echolink", "has-irlp", "has-allstar", "status-map", "club-roster", "only-club", "sponsor", "affiliate", "band", "freq-min", "freq-max", "lat", "lon", "grid", "near", "near-me", "gps", "distance", "max-distance", "from", "swap-rxtx", "match", "where", "limit", "sample", "seed"}
+
+// Options that are never recorded: the email is personal, and the rest describe the recording itself
+var transcriptExcluded = map[string]bool{"email": true, "transcript": true}
+
+type Transcript struct {
+	Version int                `json:"version"`
+	Created time.Time          `json:"created"`
+	Options map[string]string  `json:"options"`
+	Filters []string           `json:"filters"`
+	Queries []TranscriptQuery  `json:"queries"`
+	Outputs []TranscriptOutput `json:"outputs"`
+}
+
+type TranscriptQuery struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	Bytes  int    `json:"bytes"`
+}
+
+type TranscriptOutput struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	SHA256 string `json:"sha256"`
+	Bytes  int    `json:"bytes"`
+}
+
+// Captures every option that was set by flag or environment, since together they reproduce the run
+func newTranscript(fs *flag.FlagSet, config *Config) *Transcript {
+	t := &Transcript{
+		Version: transcriptVersion,
+		Created: time.Now().UTC(),
+		Options: make(map[string]string),
+	}
+	for name, source := range configSources(fs, config) {
+		if source == "default" || strings.HasPrefix(source, "auto-detected") || transcriptExcluded[name] {
+			continue
+		}
+		t.Options[name] = fs.Lookup(name).Value.String()
+	}
+	return t
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *Transcript) addQuery(url string, data []byte) {
+	if t == nil {
+		return
+	}
+	t.Queries = append(t.Queries, TranscriptQuery{URL: url, SHA256: hashBytes(data), Bytes: len(data)})
+}
+
+func (t *Transcript) addOutput(path string, format string) {
+	if t == nil {
+		return
+	}
+	output := TranscriptOutput{Path: path, Format: format}
+	if data, err := os.ReadFile(path); err == nil {
+		output.SHA256 = hashBytes(data)
+		output.Bytes = len(data)
+	}
+	t.Outputs = append(t.Outputs, output)
+}
+
+func (t *Transcript) save(path string, config *Config) error {
+	// Pin looked-up locations, since a geocoder, IP lookup or GPS may answer differently next time
+	if config.Near != "" || config.NearMe || config.GPS {
+		delete(t.Options, "near")
+		delete(t.Options, "near-me")
+		delete(t.Options, "gps")
+		delete(t.Options, "gpsd-addr")
+		t.Options["lat"] = config.Lat
+		t.Options["lon"] = config.Lon
+	}
+	if _, ok := t.Options["from"]; ok {
+		t.Options["from"] = config.From
+	}
+	t.Filters = nil
+	for _, name := range filterFlags {
+		if value, ok := t.Options[name]; ok {
+			t.Filters = append(t.Filters, name+"="+value)
+		}
+	}
+	data, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return fmt.Errorf("formatting JSON: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	return nil
+}
+
+func loadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading transcript: %w", err)
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parsing transcript: %w", err)
+	}
+	if t.Version != transcriptVersion {
+		return nil, fmt.Errorf("unsupported transcript version %d", t.Version)
+	}
+	return &t, nil
+}
+
+// Turns recorded options back into command-line flags, in a stable order
+func (t *Transcript) args() []string {
+	names := make([]string, 0, len(t.Options))
+	for name := range t.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, "--"+name+"="+t.Options[name])
+	}
+	return args
+}
+
+func runReplayCommand(args []string) int {
+	live := false
+	for len(args) > 0 && (args[0] == "--live" || args[0] == "-live") {
+		live = true
+		args = args[1:]
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: rbdl replay [--live] transcript.json [options]\n")
+		return 1
+	}
+	original, err := loadTranscript(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Options given after the transcript path override the recorded ones
+	fs := flag.NewFlagSet("rbdl replay", flag.ExitOnError)
+	config := parseFlags(fs, append(original.args(), args[1:]...))
+	if live {
+		config.CacheTTL = 0
+	} else {
+		config.CacheTTL = replayCacheTTL
+	}
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitInvalid
+	}
+	replayed := newTranscript(fs, config)
+	if code := runDownload(config, replayed); code != 0 {
+		return code
+	}
+	compareTranscripts(original, replayed)
+	return 0
+}
+
+// Reports where the replay diverged from the original run, which usually means the data changed upstream
+func compareTranscripts(original, replayed *Transcript) {
+	matched := true
+	for i, query := range replayed.Queries {
+		if i >= len(original.Queries) || original.Queries[i].SHA256 != query.SHA256 {
+			fmt.Fprintf(os.Stderr, "Warning: response for %s differs from the transcript\n", query.URL)
+			matched = false
+		}
+	}
+	for i, output := range replayed.Outputs {
+		if i >= len(original.Outputs) || original.Outputs[i].SHA256 != output.SHA256 {
+			fmt.Fprintf(os.Stderr, "Warning: output %s differs from the transcript\n", output.Path)
+			matched = false
+		}
+	}
+	if matched {
+		fmt.Println("Replay matches the transcript")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+const defaultChannelPriority = "closest,on-air,analog"
+
+// Ranks two repeaters by one --priority rule, negative when a should be kept before b
+type priorityRule func(a, b map[string]interface{}) int
+
+func parsePriority(spec string, config *Config) ([]priorityRule, error) {
+	statuses, err := parseStatusMap(config.StatusMap)
+	if err != nil {
+		return nil, err
+	}
+	flag := func(keep func(map[string]interface{}) bool) priorityRule {
+		return func(a, b map[string]interface{}) int {
+			switch ka, kb := keep(a), keep(b); {
+			case ka && !kb:
+				return -1
+			case kb && !ka:
+				return 1
+			}
+			return 0
+		}
+	}
+	var rules []priorityRule
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "closest":
+			rules = append(rules, func(a, b map[string]interface{}) int {
+				da, db := channelDistance(a, config), channelDistance(b, config)
+				switch {
+				case da < db:
+					return -1
+				case db < da:
+					return 1
+				}
+				return 0
+			})
+		case "on-air":
+			rules = append(rules, flag(func(record map[string]interface{}) bool {
+				return recordStatus(record, statuses) == statusOnAir
+			}))
+		case "analog":
+			rules = append(rules, flag(func(record map[string]interface{}) bool {
+				return recordString(record, "FM Analog") == "Yes"
+			}))
+		case "open":
+			rules = append(rules, flag(func(record map[string]interface{}) bool {
+				return strings.EqualFold(recordString(record, "Use"), "open")
+			}))
+		default:
+			return nil, fmt.Errorf("unknown --priority rule %q, expected closest, on-air, analog or open", name)
+		}
+	}
+	return rules, nil
+}
+
+// Repeaters without a known distance rank after every one with a distance
+func channelDistance(record map[string]interface{}, config *Config) float64 {
+	if lat, lon, ok := config.referencePoint(); ok {
+		if km, ok := recordDistance(record, lat, lon); ok {
+			return km
+		}
+	}
+	return math.Inf(1)
+}
+
+func validateMaxChannels(config *Config) error {
+	if config.MaxChannels < 0 {
+		return fmt.Errorf("--max-channels must be positive")
+	}
+	_, err := parsePriority(config.Priority, config)
+	return err
+}
+
+// Memories a repeater takes in a codeplug export, two with a talkaround channel
+func channelCost(record map[string]interface{}, config *Config) int {
+	if config.AddTalkaround && config.Format == "chirp" {
+		if row, ok := chirpRow(record, config); ok && row["Duplex"] != "" {
+			return 2
+		}
+	}
+	return 1
+}
+
+// Records are parsed again for each output of a run, so the trim is only reported once
+var trimNoted bool
+
+// Favorites and static channels are never trimmed, whatever --priority says
+func alwaysKept(record map[string]interface{}) bool {
+	return isFavorite(record) || isStaticChannel(record)
+}
+
+// Trims the results to --max-channels memories, keeping the other repeaters in --priority order
+// and leaving the ones kept in the order they were in
+func trimChannels(records []map[string]interface{}, config *Config) ([]map[string]interface{}, error) {
+	if config.MaxChannels == 0 {
+		return records, nil
+	}
+	// Simplex and weather channels are always kept too, so the other repeaters share what's left
+	budget := config.MaxChannels - len(simplexChannels(records, config)) - weatherChannelCount(records, config)
+	total, favorites := 0, 0
+	for _, record := range records {
+		switch {
+		case isFavorite(record):
+			favorites += channelCost(record, config)
+		case isStaticChannel(record):
+			budget -= channelCost(record, config)
+		default:
+			total += channelCost(record, config)
+		}
+	}
+	// Rather than trim a favorite, say how many memories they need
+	if favorites > config.MaxChannels {
+		return nil, fmt.Errorf("favorites need %d channels, --max-channels is %d", favorites, config.MaxChannels)
+	}
+	if budget -= favorites; budget < 0 {
+		return nil, fmt.Errorf("favorites, static, simplex and weather channels need %d channels, --max-channels is %d", config.MaxChannels-budget, config.MaxChannels)
+	}
+	if total <= budget {
+		return records, nil
+	}
+	rules, err := parsePriority(config.Priority, config)
+	if err != nil {
+		return nil, err
+	}
+	ranked := make([]int, len(records))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		for _, rule := range rules {
+			if c := rule(records[ranked[i]], records[ranked[j]]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	keep := make(map[int]bool)
+	used := 0
+	for _, i := range ranked {
+		if alwaysKept(records[i]) {
+			keep[i] = true
+		} else if cost := channelCost(records[i], config); used+cost <= budget {
+			keep[i] = true
+			used += cost
+		}
+	}
+	trimmed := make([]map[string]interface{}, 0, len(keep))
+	for i, record := range records {
+		if keep[i] {
+			trimmed = append(trimmed, record)
+		}
+	}
+	if !config.Quiet && !trimNoted {
+		trimNoted = true
+		fmt.Fprintf(os.Stderr, "Note: kept %d of %d repeaters to fit --max-channels %d\n", len(trimmed), len(records), config.MaxChannels)
+	}
+	return trimmed, nil
+}
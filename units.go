@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	unitsMetric   = "metric"
+	unitsImperial = "imperial"
+	metersPerFoot = 0.3048
+)
+
+// Elevations as RepeaterBook gives them, in feet
+var elevationFields = []string{"Elevation", "AMSL", "HAAT"}
+
+func validateUnits(config *Config) error {
+	switch config.Units {
+	case "", unitsMetric, unitsImperial:
+		return nil
+	}
+	return fmt.Errorf("--units must be metric or imperial")
+}
+
+// Rewrites elevations in meters for --units metric, rounded to the nearest meter
+func convertElevations(records []map[string]interface{}, config *Config) {
+	if config.Units != unitsMetric {
+		return
+	}
+	for _, record := range records {
+		for _, field := range elevationFields {
+			feet, ok := recordFloat(record, field)
+			if !ok {
+				continue
+			}
+			record[field] = strconv.FormatFloat(math.Round(feet*metersPerFoot), 'f', -1, 64)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Replacements for field values in the data formats, by field and then lowercased value
+type valueMap map[string]map[string]string
+
+// Parses --value-map entries such as "Operational Status:On-air=ON,FM Analog:Yes=FM"
+func parseValueMap(list string) (valueMap, error) {
+	values := make(valueMap)
+	for _, entry := range splitList(list) {
+		field, pair, ok := strings.Cut(entry, ":")
+		value, replacement, hasValue := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		if !ok || !hasValue || field == "" || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("invalid --value-map entry %q, expected field:value=replacement", entry)
+		}
+		if values[field] == nil {
+			values[field] = make(map[string]string)
+		}
+		values[field][strings.ToLower(strings.TrimSpace(value))] = strings.TrimSpace(replacement)
+	}
+	return values, nil
+}
+
+func validateValueMap(config *Config) error {
+	if config.ValueMap == "" {
+		return nil
+	}
+	if _, err := parseValueMap(config.ValueMap); err != nil {
+		return err
+	}
+	switch config.Format {
+	case "json", "csv", "msgpack", "template":
+	default:
+		return fmt.Errorf("--value-map is only supported for json, csv, msgpack and template output")
+	}
+	return nil
+}
+
+// Swaps listed values for the ones downstream tools expect, after filtering, which still sees the
+// API's own spelling. Values without a replacement are kept.
+func translateValues(records []map[string]interface{}, config *Config) {
+	if config.ValueMap == "" {
+		return
+	}
+	values, _ := parseValueMap(config.ValueMap)
+	for _, record := range records {
+		for field, replacements := range values {
+			if _, ok := record[field]; !ok {
+				continue
+			}
+			if replacement, ok := replacements[strings.ToLower(recordString(record, field))]; ok {
+				record[field] = replacement
+			}
+		}
+	}
+}
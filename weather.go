@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The seven NOAA Weather Radio and Environment Canada Weatheradio frequencies, numbered as radios
+// label them
+var weatherChannels = []float64{162.55, 162.4, 162.475, 162.425, 162.45, 162.5, 162.525}
+
+func validateWeather(config *Config) error {
+	if config.IncludeWeather && config.Format != "chirp" {
+		return fmt.Errorf("--include-weather is only supported for chirp output")
+	}
+	return nil
+}
+
+// The weather channels are only broadcast in the United States and Canada, so they're added when
+// the results have a repeater there. Listings without a country are from the North American endpoint.
+func weatherChannelCount(records []map[string]interface{}, config *Config) int {
+	if !config.IncludeWeather {
+		return 0
+	}
+	for _, record := range records {
+		switch strings.ToLower(recordString(record, "Country")) {
+		case "", "united states", "canada":
+			return len(weatherChannels)
+		}
+	}
+	return 0
+}
+
+// Receive-only CHIRP rows for the weather channels, named WX1 to WX7
+func weatherRows(records []map[string]interface{}, namer *channelNamer, config *Config) []map[string]string {
+	var rows []map[string]string
+	for i, freq := range weatherChannels[:weatherChannelCount(records, config)] {
+		row := fixedChannelRow(freq, namer.fit(fmt.Sprintf("WX%d", i+1), ""), "NOAA and Environment Canada weather broadcast")
+		row["Duplex"] = "off"
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A field name or literal on either side of a --where comparison
+type whereOperand struct {
+	field   string
+	literal string
+	isField bool
+}
+
+func (o *whereOperand) value(record map[string]interface{}) string {
+	if o.isField {
+		return recordString(record, o.field)
+	}
+	return o.literal
+}
+
+type wherePredicate func(record map[string]interface{}) bool
+
+// A compiled --where expression, with the fields it names so they can be checked against the records
+type whereFilter struct {
+	match  wherePredicate
+	fields []*whereOperand
+}
+
+type whereToken struct {
+	kind string // "field", "string", "number" or the operator itself
+	text string
+}
+
+var whereOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "=", "!", "(", ")"}
+
+func tokenizeWhere(expr string) ([]whereToken, error) {
+	var tokens []whereToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], expr[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, whereToken{"string", expr[i+1 : i+1+end]})
+			i += end + 2
+			continue
+		case c == '`':
+			// Backticks quote field names with spaces, like `Input Freq`
+			end := strings.IndexByte(expr[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field name at position %d", i+1)
+			}
+			tokens = append(tokens, whereToken{"field", expr[i+1 : i+1+end]})
+			i += end + 2
+			continue
+		case unicode.IsDigit(c) || (c == '-' || c == '.') && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1])):
+			j := i + 1
+			for j < len(expr) && (unicode.IsDigit(rune(expr[j])) || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, whereToken{"number", expr[i:j]})
+			i = j
+			continue
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j])) || expr[j] == '_' || expr[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, whereToken{"field", expr[i:j]})
+			i = j
+			continue
+		}
+		matched := false
+		for _, op := range whereOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				tokens = append(tokens, whereToken{op, op})
+				i += len(op)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+	fields []*whereOperand
+}
+
+func (p *whereParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].kind
+	}
+	return ""
+}
+
+// Compiles a --where expression: comparisons of fields and literals with == != < <= > >=, joined
+// with && and ||, negated with ! and grouped with parentheses. A field alone is true when it's set
+// to anything but No, 0 or false.
+func compileWhere(expr string) (*whereFilter, error) {
+	tokens, err := tokenizeWhere(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where: %v", err)
+	}
+	p := &whereParser{tokens: tokens}
+	match, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where: %v", err)
+	}
+	return &whereFilter{match: match, fields: p.fields}, nil
+}
+
+func (p *whereParser) or() (wherePredicate, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(record map[string]interface{}) bool { return l(record) || right(record) }
+	}
+	return left, nil
+}
+
+func (p *whereParser) and() (wherePredicate, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(record map[string]interface{}) bool { return l(record) && right(record) }
+	}
+	return left, nil
+}
+
+func (p *whereParser) unary() (wherePredicate, error) {
+	switch p.peek() {
+	case "!":
+		p.pos++
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(record map[string]interface{}) bool { return !inner(record) }, nil
+	case "(":
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+func (p *whereParser) comparison() (wherePredicate, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "=", "!=", "<", "<=", ">", ">=":
+		p.pos++
+	default:
+		if !left.isField {
+			return nil, fmt.Errorf("%q must be compared with something", left.literal)
+		}
+		return func(record map[string]interface{}) bool { return whereTruthy(left.value(record)) }, nil
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(record map[string]interface{}) bool {
+		c := compareWhereValues(left.value(record), right.value(record))
+		switch op {
+		case "==", "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		}
+		return c >= 0
+	}, nil
+}
+
+func (p *whereParser) operand() (*whereOperand, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expression ends early")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case "field":
+		operand := &whereOperand{field: token.text, isField: true}
+		p.fields = append(p.fields, operand)
+		return operand, nil
+	case "string", "number":
+		return &whereOperand{literal: token.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token.text)
+}
+
+// Compares as numbers when both sides are numbers, otherwise as text without regard to case
+func compareWhereValues(x, y string) int {
+	if m, err := strconv.ParseFloat(x, 64); err == nil {
+		if n, err := strconv.ParseFloat(y, 64); err == nil {
+			switch {
+			case m < n:
+				return -1
+			case m > n:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(x), strings.ToLower(y))
+}
+
+func whereTruthy(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "no", "0", "false":
+		return false
+	}
+	return true
+}
+
+// Field names must exist on at least one record, so a typo doesn't silently match nothing
+func filterWhere(records []map[string]interface{}, filter *whereFilter) ([]map[string]interface{}, error) {
+	for _, operand := range filter.fields {
+		field, ok := findField(records, operand.field)
+		if !ok {
+			return nil, fmt.Errorf("--where names a field %s, which no repeater has (quote text values, as in %q)", operand.field, operand.field)
+		}
+		operand.field = field
+	}
+	return filterRecords(records, filter.match), nil
+}
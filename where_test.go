@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileWhere(t *testing.T) {
+	records := []map[string]interface{}{
+		{"Callsign": "W7AAA", "Frequency": "146.88", "Input Freq": "146.28", "PL": "100.0", "DMR": "No", "Offset": "-0.600"},
+		{"Callsign": "W7BBB", "Frequency": "444.95", "Input Freq": "449.95", "PL": "", "DMR": "Yes", "Offset": "5.000"},
+		{"Callsign": "K7CCC", "Frequency": "147.00", "Input Freq": "147.60", "PL": "123.0", "DMR": "No", "Offset": "0.600"},
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// && binds tighter than ||
+		{`Callsign == "W7BBB" || DMR == "No" && PL == "123.0"`, "W7BBB,K7CCC"},
+		{`(Callsign == "W7BBB" || DMR == "No") && PL == "123.0"`, "K7CCC"},
+		{`!DMR`, "W7AAA,K7CCC"},
+		{`!(Frequency > 400 || PL == "100.0")`, "K7CCC"},
+		{`!!DMR`, "W7BBB"},
+		{"`Input Freq` > 147", "W7BBB,K7CCC"},
+		{"`Input Freq` = 146.28", "W7AAA"},
+		{`Offset < -0.5`, "W7AAA"},
+		{`Offset >= -0.6 && Offset <= 0.6`, "W7AAA,K7CCC"},
+		// Numbers compare by value, text without regard to case
+		{`Frequency < 200`, "W7AAA,K7CCC"},
+		{`Frequency == 146.880`, "W7AAA"},
+		{`Callsign == 'w7aaa'`, "W7AAA"},
+		{`Callsign >= "W"`, "W7AAA,W7BBB"},
+		{`PL != ""`, "W7AAA,K7CCC"},
+		{`PL`, "W7AAA,K7CCC"},
+	}
+	for _, test := range tests {
+		filter, err := compileWhere(test.expr)
+		if err != nil {
+			t.Errorf("compileWhere(%s): %v", test.expr, err)
+			continue
+		}
+		matched, err := filterWhere(records, filter)
+		if err != nil {
+			t.Errorf("filterWhere(%s): %v", test.expr, err)
+			continue
+		}
+		var calls []string
+		for _, record := range matched {
+			calls = append(calls, recordString(record, "Callsign"))
+		}
+		if got := strings.Join(calls, ","); got != test.want {
+			t.Errorf("--where %s matched %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestCompileWhereErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`Callsign == "W7AAA`, "unterminated string at position 13"},
+		{`Callsign == 'W7AAA`, "unterminated string at position 13"},
+		{"`Input Freq > 147", "unterminated field name at position 1"},
+		{`(PL == "100.0"`, "missing )"},
+		{`PL == "100.0")`, `unexpected ")"`},
+		{`PL ==`, "expression ends early"},
+		{`"100.0"`, `"100.0" must be compared with something`},
+		{`PL == "100.0" &&`, "expression ends early"},
+		{`PL # 100`, `unexpected '#' at position 4`},
+		{`== PL`, `unexpected "=="`},
+	}
+	for _, test := range tests {
+		_, err := compileWhere(test.expr)
+		if err == nil {
+			t.Errorf("compileWhere(%s) succeeded, want an error", test.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("compileWhere(%s) = %v, want %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestFilterWhereUnknownField(t *testing.T) {
+	records := []map[string]interface{}{{"Callsign": "W7AAA", "Frequency": "146.88"}}
+	filter, err := compileWhere(`Callsign == W7AAA`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = filterWhere(records, filter)
+	if err == nil || !strings.Contains(err.Error(), "names a field W7AAA, which no repeater has") {
+		t.Errorf("filterWhere = %v, want an unknown field error for W7AAA", err)
+	}
+	// Field names are matched without regard to case
+	filter, err = compileWhere(`frequency > 100`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched, err := filterWhere(records, filter); err != nil || len(matched) != 1 {
+		t.Errorf("filterWhere(frequency > 100) = %d records, %v, want 1", len(matched), err)
+	}
+}
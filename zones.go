@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Derived field naming the DMR zone a repeater is assigned to, with --zones
+const zoneField = "zone"
+
+const (
+	defaultZoneSize = 16
+	defaultZoneRing = "25mi"
+	// Radios show zone and scan list names in the same 16 characters as channel names
+	zoneNameLength = 16
+)
+
+// Channels a zone holds on common DMR radios, for --zone-size
+var radioZoneSizes = map[string]int{
+	"md380":    16,
+	"md390":    16,
+	"md2017":   16,
+	"anytone":  250,
+	"at878":    250,
+	"at578":    250,
+	"opengd77": 80,
+}
+
+// A zone size given as a number, or as a radio model like md380 or AT-D878UV
+func parseZoneSize(spec string) (int, error) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n <= 0 {
+			return 0, fmt.Errorf("--zone-size must be a positive number or a radio model")
+		}
+		return n, nil
+	}
+	model := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, spec)
+	for radio, size := range radioZoneSizes {
+		if strings.HasPrefix(model, radio) || strings.HasPrefix(model, "atd"+strings.TrimPrefix(radio, "at")) {
+			return size, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown radio %q for --zone-size, give a number of channels", spec)
+}
+
+func validateZones(config *Config) error {
+	if config.Zones == "" {
+		return nil
+	}
+	switch config.Zones {
+	case "county", "city", "distance", "linked":
+	default:
+		return fmt.Errorf("--zones must be one of: county, city, distance or linked")
+	}
+	if _, err := parseZoneSize(config.ZoneSize); err != nil {
+		return err
+	}
+	if config.Zones == "distance" {
+		if _, err := parseDistance(config.ZoneRing); err != nil {
+			return fmt.Errorf("--zone-ring: %w", err)
+		}
+		if !hasLocation(config) && config.From == "" {
+			return fmt.Errorf("--zones distance needs a location to measure from, such as --grid, --near or --from")
+		}
+	}
+	return nil
+}
+
+// Groups DMR repeaters into zones by county, city, distance ring or linked system, in the order
+// they're listed. Groups larger than --zone-size carry on in numbered zones, e.g. Gallatin, Gallatin 2.
+func assignZones(records []map[string]interface{}, config *Config) {
+	if config.Zones == "" {
+		return
+	}
+	size, _ := parseZoneSize(config.ZoneSize)
+	counts := make(map[string]int)
+	for _, record := range records {
+		if recordString(record, "DMR") != "Yes" {
+			continue
+		}
+		group := zoneGroup(record, config)
+		record[zoneField] = numberedName(group, counts[group], size)
+		counts[group]++
+	}
+}
+
+// Names the zone or scan list holding the nth member of a group, numbering those after the first
+// and cutting names to the 16 characters radios show
+func numberedName(group string, n, size int) string {
+	suffix := ""
+	if part := n/size + 1; part > 1 {
+		suffix = " " + strconv.Itoa(part)
+	}
+	return strings.TrimSpace(truncate(group, zoneNameLength-len(suffix))) + suffix
+}
+
+func zoneGroup(record map[string]interface{}, config *Config) string {
+	switch config.Zones {
+	case "county":
+		if county := recordString(record, "County"); county != "" {
+			return county
+		}
+	case "city":
+		if city := recordString(record, "Nearest City"); city != "" {
+			return city
+		}
+	case "linked":
+		if system := recordString(record, linkedSystemField); system != "" {
+			return system
+		}
+	case "distance":
+		lat, lon, ok := config.referencePoint()
+		if !ok {
+			break
+		}
+		km, ok := recordDistance(record, lat, lon)
+		if !ok {
+			break
+		}
+		ring, _ := parseDistance(config.ZoneRing)
+		value, unit := splitDistance(config.ZoneRing)
+		width, _ := strconv.ParseFloat(value, 64)
+		i := math.Floor(km / ring)
+		return fmt.Sprintf("%g-%g %s", i*width, (i+1)*width, unit)
+	}
+	return "Other"
+}